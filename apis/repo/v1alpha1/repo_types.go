@@ -2,12 +2,14 @@ package v1alpha1
 
 import (
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/krateoplatformops/provider-git/apis/v1alpha1"
 	"github.com/krateoplatformops/provider-git/pkg/helpers"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type RepoOpts struct {
-	// Url: the repository URL.
+	// Url: the repository URL; 'file://' URLs and filesystem paths of
+	// local repositories are supported too, with no credentials.
 	// +immutable
 	Url string `json:"url"`
 
@@ -18,23 +20,138 @@ type RepoOpts struct {
 	Path *string `json:"path,omitempty"`
 }
 
+type FromRepoOpts struct {
+	RepoOpts `json:",inline"`
+
+	// Ref: the branch, tag or full commit SHA to copy from (default: the remote HEAD).
+	// +optional
+	// +immutable
+	Ref *string `json:"ref,omitempty"`
+
+	// RecurseSubmodules: checks out the submodules too, recursively, fetching
+	// them with the fromRepo credentials; their files are copied and rendered
+	// as the other ones (default: false).
+	// +optional
+	// +immutable
+	RecurseSubmodules *bool `json:"recurseSubmodules,omitempty"`
+}
+
+type ToRepoOpts struct {
+	RepoOpts `json:",inline"`
+
+	// Branch: the branch to write to (default: the remote HEAD branch).
+	// +optional
+	// +immutable
+	Branch *string `json:"branch,omitempty"`
+
+	// PushPolicy: 'Rebase' replays the commit on the branch tip if the branch
+	// moved meanwhile, 'Force' overwrites the branch (default: Rebase).
+	// +kubebuilder:validation:Enum=Rebase;Force
+	// +optional
+	PushPolicy *string `json:"pushPolicy,omitempty"`
+
+	// CreateIfMissing: creates the repository through the ProviderConfig
+	// 'toRepoApi' if it does not exist.
+	// +optional
+	// +immutable
+	CreateIfMissing *CreateRepoOpts `json:"createIfMissing,omitempty"`
+
+	// Delivery: how the scaffold reaches the branch (default: pushed to the branch).
+	// +optional
+	// +immutable
+	Delivery *DeliveryOpts `json:"delivery,omitempty"`
+}
+
+type DeliveryOpts struct {
+	// Mode: 'Push' pushes the commit to the branch, 'PullRequest' pushes it to
+	// a feature branch and opens a pull (merge) request to the branch through
+	// the ProviderConfig 'toRepoApi' (default: Push).
+	// +kubebuilder:validation:Enum=Push;PullRequest
+	// +optional
+	Mode *string `json:"mode,omitempty"`
+
+	// FeatureBranch: the pull request source branch (default: 'krateo/<deploymentId>').
+	// +optional
+	FeatureBranch *string `json:"featureBranch,omitempty"`
+
+	// Title: the pull request title (default: the commit message first line).
+	// +optional
+	Title *string `json:"title,omitempty"`
+
+	// AvailableWhen: the Repo becomes Available when the pull request is
+	// 'Opened' or 'Merged' (default: Merged).
+	// +kubebuilder:validation:Enum=Opened;Merged
+	// +optional
+	AvailableWhen *string `json:"availableWhen,omitempty"`
+}
+
+type CreateRepoOpts struct {
+	// Owner: the organization, group (GitLab), project key (Bitbucket Server)
+	// or user owning the repository (default: the repository URL path).
+	// +optional
+	Owner *string `json:"owner,omitempty"`
+
+	// Visibility: the repository visibility (default: private).
+	// +kubebuilder:validation:Enum=private;internal;public
+	// +optional
+	Visibility *string `json:"visibility,omitempty"`
+
+	// Description: the repository description.
+	// +optional
+	Description *string `json:"description,omitempty"`
+}
+
 type RepoParameters struct {
 	// FromRepo: .
 	// +immutable
-	FromRepo RepoOpts `json:"fromRepo"`
+	FromRepo FromRepoOpts `json:"fromRepo"`
 
 	// ToRepo: .
 	// +immutable
-	ToRepo RepoOpts `json:"toRepo"`
+	ToRepo ToRepoOpts `json:"toRepo"`
 
 	// ConfigMapKeyRef: holds template values
 	// +optional
 	ConfigMapKeyRef *helpers.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// Commit: overrides the ProviderConfig commit identity and message.
+	// +optional
+	Commit *v1alpha1.CommitOpts `json:"commit,omitempty"`
+
+	// Tag: the annotated tag created on the scaffold commit and pushed
+	// to the target repo, unless it already exists.
+	// +optional
+	// +immutable
+	Tag *TagOpts `json:"tag,omitempty"`
+}
+
+type TagOpts struct {
+	// Name: the tag name (i.e. 'v0.0.1').
+	Name string `json:"name"`
+
+	// Message: the tag message (default: the tag name).
+	// +optional
+	Message *string `json:"message,omitempty"`
 }
 
 type RepoObservation struct {
 	// DeploymentId: correlation identifier with UI
 	DeploymentId *string `json:"deploymentId,omitempty"`
+
+	// Branch: the target repo branch actually written
+	Branch *string `json:"branch,omitempty"`
+
+	// CommitId: the last target branch commit SHA known to hold the scaffold
+	CommitId *string `json:"commitId,omitempty"`
+
+	// FromRepoCommitId: the origin repo commit SHA used to scaffold the target repo
+	FromRepoCommitId *string `json:"fromRepoCommitId,omitempty"`
+
+	// PullRequestUrl: the pull request delivering the scaffold
+	PullRequestUrl *string `json:"pullRequestUrl,omitempty"`
+
+	// PullRequestState: the pull request state (open, merged or closed)
+	PullRequestState *string `json:"pullRequestState,omitempty"`
 }
 
 // A RepoSpec defines the desired state of a Repo.
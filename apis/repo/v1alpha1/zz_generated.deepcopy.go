@@ -22,10 +22,102 @@ limitations under the License.
 package v1alpha1
 
 import (
+	apisv1alpha1 "github.com/krateoplatformops/provider-git/apis/v1alpha1"
 	"github.com/krateoplatformops/provider-git/pkg/helpers"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CreateRepoOpts) DeepCopyInto(out *CreateRepoOpts) {
+	*out = *in
+	if in.Owner != nil {
+		in, out := &in.Owner, &out.Owner
+		*out = new(string)
+		**out = **in
+	}
+	if in.Visibility != nil {
+		in, out := &in.Visibility, &out.Visibility
+		*out = new(string)
+		**out = **in
+	}
+	if in.Description != nil {
+		in, out := &in.Description, &out.Description
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CreateRepoOpts.
+func (in *CreateRepoOpts) DeepCopy() *CreateRepoOpts {
+	if in == nil {
+		return nil
+	}
+	out := new(CreateRepoOpts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeliveryOpts) DeepCopyInto(out *DeliveryOpts) {
+	*out = *in
+	if in.Mode != nil {
+		in, out := &in.Mode, &out.Mode
+		*out = new(string)
+		**out = **in
+	}
+	if in.FeatureBranch != nil {
+		in, out := &in.FeatureBranch, &out.FeatureBranch
+		*out = new(string)
+		**out = **in
+	}
+	if in.Title != nil {
+		in, out := &in.Title, &out.Title
+		*out = new(string)
+		**out = **in
+	}
+	if in.AvailableWhen != nil {
+		in, out := &in.AvailableWhen, &out.AvailableWhen
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeliveryOpts.
+func (in *DeliveryOpts) DeepCopy() *DeliveryOpts {
+	if in == nil {
+		return nil
+	}
+	out := new(DeliveryOpts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FromRepoOpts) DeepCopyInto(out *FromRepoOpts) {
+	*out = *in
+	in.RepoOpts.DeepCopyInto(&out.RepoOpts)
+	if in.Ref != nil {
+		in, out := &in.Ref, &out.Ref
+		*out = new(string)
+		**out = **in
+	}
+	if in.RecurseSubmodules != nil {
+		in, out := &in.RecurseSubmodules, &out.RecurseSubmodules
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FromRepoOpts.
+func (in *FromRepoOpts) DeepCopy() *FromRepoOpts {
+	if in == nil {
+		return nil
+	}
+	out := new(FromRepoOpts)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Repo) DeepCopyInto(out *Repo) {
 	*out = *in
@@ -93,6 +185,31 @@ func (in *RepoObservation) DeepCopyInto(out *RepoObservation) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.Branch != nil {
+		in, out := &in.Branch, &out.Branch
+		*out = new(string)
+		**out = **in
+	}
+	if in.CommitId != nil {
+		in, out := &in.CommitId, &out.CommitId
+		*out = new(string)
+		**out = **in
+	}
+	if in.FromRepoCommitId != nil {
+		in, out := &in.FromRepoCommitId, &out.FromRepoCommitId
+		*out = new(string)
+		**out = **in
+	}
+	if in.PullRequestUrl != nil {
+		in, out := &in.PullRequestUrl, &out.PullRequestUrl
+		*out = new(string)
+		**out = **in
+	}
+	if in.PullRequestState != nil {
+		in, out := &in.PullRequestState, &out.PullRequestState
+		*out = new(string)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepoObservation.
@@ -135,6 +252,16 @@ func (in *RepoParameters) DeepCopyInto(out *RepoParameters) {
 		*out = new(helpers.ConfigMapKeySelector)
 		**out = **in
 	}
+	if in.Commit != nil {
+		in, out := &in.Commit, &out.Commit
+		*out = new(apisv1alpha1.CommitOpts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tag != nil {
+		in, out := &in.Tag, &out.Tag
+		*out = new(TagOpts)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepoParameters.
@@ -180,3 +307,59 @@ func (in *RepoStatus) DeepCopy() *RepoStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TagOpts) DeepCopyInto(out *TagOpts) {
+	*out = *in
+	if in.Message != nil {
+		in, out := &in.Message, &out.Message
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TagOpts.
+func (in *TagOpts) DeepCopy() *TagOpts {
+	if in == nil {
+		return nil
+	}
+	out := new(TagOpts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ToRepoOpts) DeepCopyInto(out *ToRepoOpts) {
+	*out = *in
+	in.RepoOpts.DeepCopyInto(&out.RepoOpts)
+	if in.Branch != nil {
+		in, out := &in.Branch, &out.Branch
+		*out = new(string)
+		**out = **in
+	}
+	if in.PushPolicy != nil {
+		in, out := &in.PushPolicy, &out.PushPolicy
+		*out = new(string)
+		**out = **in
+	}
+	if in.CreateIfMissing != nil {
+		in, out := &in.CreateIfMissing, &out.CreateIfMissing
+		*out = new(CreateRepoOpts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Delivery != nil {
+		in, out := &in.Delivery, &out.Delivery
+		*out = new(DeliveryOpts)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ToRepoOpts.
+func (in *ToRepoOpts) DeepCopy() *ToRepoOpts {
+	if in == nil {
+		return nil
+	}
+	out := new(ToRepoOpts)
+	in.DeepCopyInto(out)
+	return out
+}
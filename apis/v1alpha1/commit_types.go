@@ -0,0 +1,27 @@
+package v1alpha1
+
+// Identity of a commit author or committer.
+type Identity struct {
+	// Name: the identity name.
+	Name string `json:"name"`
+
+	// Email: the identity email.
+	Email string `json:"email"`
+}
+
+// CommitOpts configures the commits pushed to the target repo.
+type CommitOpts struct {
+	// Author: the commits author (default: krateoctl <krateoctl@krateoplatformops.io>).
+	// +optional
+	Author *Identity `json:"author,omitempty"`
+
+	// Committer: the commits committer (default: the author).
+	// +optional
+	Committer *Identity `json:"committer,omitempty"`
+
+	// MessageTemplate: the commit message mustache template, rendered with
+	// the same values passed to the file templates; 'deploymentId' is
+	// available too (default: ':rocket: first commit').
+	// +optional
+	MessageTemplate *string `json:"messageTemplate,omitempty"`
+}
@@ -0,0 +1,22 @@
+package v1alpha1
+
+import (
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// ToRepoApi describes the REST API of the target repo hosting service.
+type ToRepoApi struct {
+	// Kind of hosting service: 'github', 'gitlab', 'bitbucket' (Bitbucket Server) or 'gitea'.
+	// +kubebuilder:validation:Enum=github;gitlab;bitbucket;gitea
+	Kind string `json:"kind"`
+
+	// Url: the REST API base URL (i.e. 'https://api.github.com',
+	// 'https://gitlab.example.com/api/v4', 'https://bitbucket.example.com/rest/api/1.0'
+	// or 'https://gitea.example.com/api/v1').
+	Url string `json:"url"`
+
+	// TokenSecretRef: the API access token (default: the toRepoCredentials
+	// token; required with the 'ssh' auth method).
+	// +optional
+	TokenSecretRef *xpv1.SecretKeySelector `json:"tokenSecretRef,omitempty"`
+}
@@ -4,6 +4,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/krateoplatformops/provider-git/pkg/helpers"
 )
 
 // RepoCredentials required to authenticate.
@@ -14,9 +15,19 @@ type RepoCredentials struct {
 
 	xpv1.CommonCredentialSelectors `json:",inline"`
 
-	// AuthMethod defines the authentication mode. One of 'basic' or 'bearer'
+	// AuthMethod defines the authentication mode. One of 'basic', 'bearer' or 'ssh'.
+	// With 'ssh' the referenced secret key must hold a PEM encoded private key.
 	// +optional
 	AuthMethod *string `json:"authMethod,omitempty"`
+
+	// PassphraseSecretRef: the passphrase of the ssh private key (only for 'ssh' auth method).
+	// +optional
+	PassphraseSecretRef *xpv1.SecretKeySelector `json:"passphraseSecretRef,omitempty"`
+
+	// KnownHosts: known_hosts entries used to verify the ssh server host key
+	// (required for 'ssh' auth method).
+	// +optional
+	KnownHosts *string `json:"knownHosts,omitempty"`
 }
 
 // A ProviderConfigSpec defines the desired state of a ProviderConfig.
@@ -31,9 +42,95 @@ type ProviderConfigSpec struct {
 	// ToCredentials required to authenticate ReST API git server.
 	ToRepoCredentials *RepoCredentials `json:"toRepoCredentials,omitempty"`
 
+	// ToRepoApi: the REST API of the target repo hosting service, used to
+	// create the missing repositories.
+	// +optional
+	ToRepoApi *ToRepoApi `json:"toRepoApi,omitempty"`
+
 	// Insecure is useful with hand made SSL certs (default: false)
 	// +optional
 	Insecure *bool `json:"insecure,omitempty"`
+
+	// CABundleRef: PEM encoded CA certificates trusted, in addition to the system ones,
+	// by the git operations and by the deployment service client.
+	// +optional
+	CABundleRef *CABundleSelector `json:"caBundleRef,omitempty"`
+
+	// ProxyUrl: the HTTP(S) proxy used for every outbound call
+	// (default: the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables).
+	// +optional
+	ProxyUrl *string `json:"proxyUrl,omitempty"`
+
+	// ProxyCredentialsSecretRef: the proxy credentials, formatted as 'username:password'.
+	// +optional
+	ProxyCredentialsSecretRef *xpv1.SecretKeySelector `json:"proxyCredentialsSecretRef,omitempty"`
+
+	// NoProxy: hosts, domains (i.e. '.example.com'), IP addresses or CIDRs reached without proxy.
+	// +optional
+	NoProxy []string `json:"noProxy,omitempty"`
+
+	// Signing: the key used to sign the commits and the annotated tags.
+	// +optional
+	Signing *SigningConfig `json:"signing,omitempty"`
+
+	// Commit: the identity and the message of the commits
+	// (each field can be overridden by the Repo).
+	// +optional
+	Commit *CommitOpts `json:"commit,omitempty"`
+
+	// Timeouts: the per-operation timeouts (default: bounded only by the reconcile deadline).
+	// +optional
+	Timeouts *Timeouts `json:"timeouts,omitempty"`
+}
+
+// Timeouts of the git and deployment service operations (i.e. '30s', '2m').
+type Timeouts struct {
+	// Clone: each repository clone.
+	// +optional
+	Clone *metav1.Duration `json:"clone,omitempty"`
+
+	// Fetch: each remote references listing or single commit fetch.
+	// +optional
+	Fetch *metav1.Duration `json:"fetch,omitempty"`
+
+	// Push: each push, including the commit replays.
+	// +optional
+	Push *metav1.Duration `json:"push,omitempty"`
+
+	// Deployment: each deployment service call.
+	// +optional
+	Deployment *metav1.Duration `json:"deployment,omitempty"`
+
+	// Hosting: each hosting service API call (repository creation and pull requests).
+	// +optional
+	Hosting *metav1.Duration `json:"hosting,omitempty"`
+}
+
+// SigningConfig references the key used to sign commits and tags.
+type SigningConfig struct {
+	// Format of the signing key: 'openpgp' (armored private key) or
+	// 'ssh' (PEM or OpenSSH private key) (default: openpgp).
+	// +kubebuilder:validation:Enum=openpgp;ssh
+	// +optional
+	Format *string `json:"format,omitempty"`
+
+	// KeySecretRef: the secret key holding the private key.
+	KeySecretRef xpv1.SecretKeySelector `json:"keySecretRef"`
+
+	// PassphraseSecretRef: the passphrase of the private key, if encrypted.
+	// +optional
+	PassphraseSecretRef *xpv1.SecretKeySelector `json:"passphraseSecretRef,omitempty"`
+}
+
+// A CABundleSelector references PEM encoded CA certificates held by a ConfigMap or by a Secret.
+type CABundleSelector struct {
+	// ConfigMapKeyRef: the configmap key holding the certificates.
+	// +optional
+	ConfigMapKeyRef *helpers.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// SecretKeyRef: the secret key holding the certificates.
+	// +optional
+	SecretKeyRef *xpv1.SecretKeySelector `json:"secretKeyRef,omitempty"`
 }
 
 // A ProviderConfigStatus reflects the observed state of a ProviderConfig.
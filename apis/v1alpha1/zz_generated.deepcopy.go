@@ -22,9 +22,82 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/krateoplatformops/provider-git/pkg/helpers"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CABundleSelector) DeepCopyInto(out *CABundleSelector) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(helpers.ConfigMapKeySelector)
+		**out = **in
+	}
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(v1.SecretKeySelector)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CABundleSelector.
+func (in *CABundleSelector) DeepCopy() *CABundleSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(CABundleSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CommitOpts) DeepCopyInto(out *CommitOpts) {
+	*out = *in
+	if in.Author != nil {
+		in, out := &in.Author, &out.Author
+		*out = new(Identity)
+		**out = **in
+	}
+	if in.Committer != nil {
+		in, out := &in.Committer, &out.Committer
+		*out = new(Identity)
+		**out = **in
+	}
+	if in.MessageTemplate != nil {
+		in, out := &in.MessageTemplate, &out.MessageTemplate
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CommitOpts.
+func (in *CommitOpts) DeepCopy() *CommitOpts {
+	if in == nil {
+		return nil
+	}
+	out := new(CommitOpts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Identity) DeepCopyInto(out *Identity) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Identity.
+func (in *Identity) DeepCopy() *Identity {
+	if in == nil {
+		return nil
+	}
+	out := new(Identity)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProviderConfig) DeepCopyInto(out *ProviderConfig) {
 	*out = *in
@@ -97,11 +170,51 @@ func (in *ProviderConfigSpec) DeepCopyInto(out *ProviderConfigSpec) {
 		*out = new(RepoCredentials)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ToRepoApi != nil {
+		in, out := &in.ToRepoApi, &out.ToRepoApi
+		*out = new(ToRepoApi)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Insecure != nil {
 		in, out := &in.Insecure, &out.Insecure
 		*out = new(bool)
 		**out = **in
 	}
+	if in.CABundleRef != nil {
+		in, out := &in.CABundleRef, &out.CABundleRef
+		*out = new(CABundleSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProxyUrl != nil {
+		in, out := &in.ProxyUrl, &out.ProxyUrl
+		*out = new(string)
+		**out = **in
+	}
+	if in.ProxyCredentialsSecretRef != nil {
+		in, out := &in.ProxyCredentialsSecretRef, &out.ProxyCredentialsSecretRef
+		*out = new(v1.SecretKeySelector)
+		**out = **in
+	}
+	if in.NoProxy != nil {
+		in, out := &in.NoProxy, &out.NoProxy
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Signing != nil {
+		in, out := &in.Signing, &out.Signing
+		*out = new(SigningConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Commit != nil {
+		in, out := &in.Commit, &out.Commit
+		*out = new(CommitOpts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Timeouts != nil {
+		in, out := &in.Timeouts, &out.Timeouts
+		*out = new(Timeouts)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderConfigSpec.
@@ -197,6 +310,16 @@ func (in *RepoCredentials) DeepCopyInto(out *RepoCredentials) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.PassphraseSecretRef != nil {
+		in, out := &in.PassphraseSecretRef, &out.PassphraseSecretRef
+		*out = new(v1.SecretKeySelector)
+		**out = **in
+	}
+	if in.KnownHosts != nil {
+		in, out := &in.KnownHosts, &out.KnownHosts
+		*out = new(string)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepoCredentials.
@@ -208,3 +331,89 @@ func (in *RepoCredentials) DeepCopy() *RepoCredentials {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SigningConfig) DeepCopyInto(out *SigningConfig) {
+	*out = *in
+	if in.Format != nil {
+		in, out := &in.Format, &out.Format
+		*out = new(string)
+		**out = **in
+	}
+	out.KeySecretRef = in.KeySecretRef
+	if in.PassphraseSecretRef != nil {
+		in, out := &in.PassphraseSecretRef, &out.PassphraseSecretRef
+		*out = new(v1.SecretKeySelector)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SigningConfig.
+func (in *SigningConfig) DeepCopy() *SigningConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(SigningConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Timeouts) DeepCopyInto(out *Timeouts) {
+	*out = *in
+	if in.Clone != nil {
+		in, out := &in.Clone, &out.Clone
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Fetch != nil {
+		in, out := &in.Fetch, &out.Fetch
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Push != nil {
+		in, out := &in.Push, &out.Push
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Deployment != nil {
+		in, out := &in.Deployment, &out.Deployment
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Hosting != nil {
+		in, out := &in.Hosting, &out.Hosting
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Timeouts.
+func (in *Timeouts) DeepCopy() *Timeouts {
+	if in == nil {
+		return nil
+	}
+	out := new(Timeouts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ToRepoApi) DeepCopyInto(out *ToRepoApi) {
+	*out = *in
+	if in.TokenSecretRef != nil {
+		in, out := &in.TokenSecretRef, &out.TokenSecretRef
+		*out = new(v1.SecretKeySelector)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ToRepoApi.
+func (in *ToRepoApi) DeepCopy() *ToRepoApi {
+	if in == nil {
+		return nil
+	}
+	out := new(ToRepoApi)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
 	"github.com/krateoplatformops/provider-git/apis"
+	gitclient "github.com/krateoplatformops/provider-git/pkg/clients/git"
 	git "github.com/krateoplatformops/provider-git/pkg/controller"
 )
 
@@ -25,6 +26,9 @@ func main() {
 		pollInterval     = app.Flag("poll", "How often individual resources will be checked for drift from the desired state").Default("5m").Duration()
 		maxReconcileRate = app.Flag("max-reconcile-rate", "The global maximum rate per second at which resources may checked for drift from the desired state.").Default("2").Int()
 		leaderElection   = app.Flag("leader-election", "Use leader election for the controller manager.").Short('l').Default("false").OverrideDefaultFromEnvar("LEADER_ELECTION").Bool()
+		cacheDir         = app.Flag("cache-dir", "Directory where cloned repositories are cached (i.e. an emptyDir or PVC mount); disabled if empty.").Default("").String()
+		localReposRoot   = app.Flag("local-repos-root", "Directory holding the local (file:// or path) repositories, such as a mounted volume; disabled if empty.").Default("").String()
+		cacheMaxSize     = app.Flag("cache-max-size", "Size beyond which the least recently used cached repositories are evicted, such as 512MB or 2GB.").Default("1GB").Bytes()
 	)
 	kingpin.MustParse(app.Parse(os.Args[1:]))
 
@@ -60,6 +64,15 @@ func main() {
 		Features:                &feature.Flags{},
 	}
 
-	kingpin.FatalIfError(git.Setup(mgr, o), "Cannot setup Git controllers")
+	kingpin.FatalIfError(gitclient.AllowLocalRepos(*localReposRoot), "Cannot set local repositories root")
+
+	var cache *gitclient.Cache
+	if len(*cacheDir) > 0 {
+		cache, err = gitclient.NewCache(*cacheDir, int64(*cacheMaxSize))
+		kingpin.FatalIfError(err, "Cannot create repositories cache")
+		log.Debug("Caching repositories", "dir", *cacheDir, "max-size", cacheMaxSize.String())
+	}
+
+	kingpin.FatalIfError(git.Setup(mgr, o, cache), "Cannot setup Git controllers")
 	kingpin.FatalIfError(mgr.Start(ctrl.SetupSignalHandler()), "Cannot start controller manager")
 }
@@ -3,15 +3,20 @@ package clients
 import (
 	"context"
 	"fmt"
+	gohttp "net/http"
 	"strings"
+	"time"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/krateoplatformops/provider-git/apis/v1alpha1"
+	"github.com/krateoplatformops/provider-git/pkg/clients/git"
+	"github.com/krateoplatformops/provider-git/pkg/clients/hosting"
 	"github.com/krateoplatformops/provider-git/pkg/helpers"
 	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -21,6 +26,20 @@ type Config struct {
 	DeploymentServiceUrl string
 	FromRepoCreds        transport.AuthMethod
 	ToRepoCreds          transport.AuthMethod
+	HTTPClient           *gohttp.Client
+	Signer               git.Signer
+	Commit               *v1alpha1.CommitOpts
+	Hosting              hosting.Provider
+	Timeouts             Timeouts
+}
+
+// Timeouts of the operations; zero means no timeout.
+type Timeouts struct {
+	Clone      time.Duration
+	Fetch      time.Duration
+	Push       time.Duration
+	Deployment time.Duration
+	Hosting    time.Duration
 }
 
 // GetConfig constructs a RepoCreds pair that can be used to authenticate to the git provider.
@@ -54,6 +73,25 @@ func useProviderConfig(ctx context.Context, k client.Client, mg resource.Managed
 	ret := &Config{
 		Insecure:             helpers.BoolValue(pc.Spec.Insecure),
 		DeploymentServiceUrl: pc.Spec.DeploymentServiceUrl,
+		Commit:               pc.Spec.Commit.DeepCopy(),
+		Timeouts:             getTimeouts(pc.Spec.Timeouts),
+	}
+
+	caBundle, err := getCABundle(ctx, k, pc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "retrieving CA bundle")
+	}
+
+	proxy, err := getProxyFunc(ctx, k, pc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "retrieving proxy configuration")
+	}
+
+	ret.HTTPClient = newHTTPClient(caBundle, ret.Insecure, proxy)
+
+	ret.Signer, err = getSigner(ctx, k, pc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "retrieving signing key")
 	}
 
 	ret.FromRepoCreds, err = getFromRepoCredentials(ctx, k, pc)
@@ -66,6 +104,11 @@ func useProviderConfig(ctx context.Context, k client.Client, mg resource.Managed
 		return nil, errors.Wrapf(err, "retrieving to repo credentials")
 	}
 
+	ret.Hosting, err = getHostingProvider(ctx, k, pc, ret.ToRepoCreds, ret.HTTPClient)
+	if err != nil {
+		return nil, errors.Wrapf(err, "retrieving to repo api")
+	}
+
 	return ret, nil
 }
 
@@ -90,7 +133,10 @@ func getFromRepoCredentials(ctx context.Context, k client.Client, pc *v1alpha1.P
 		return nil, err
 	}
 
-	fmt.Printf("authMethod: %s\n", authMethod)
+	if strings.EqualFold(authMethod, "ssh") {
+		return getSSHAuthMethod(ctx, k, pc.Spec.FromRepoCredentials, token)
+	}
+
 	if strings.EqualFold(authMethod, "bearer") {
 		return &http.TokenAuth{
 			Token: token,
@@ -124,6 +170,10 @@ func getToRepoCredentials(ctx context.Context, k client.Client, pc *v1alpha1.Pro
 		return nil, err
 	}
 
+	if strings.EqualFold(authMethod, "ssh") {
+		return getSSHAuthMethod(ctx, k, pc.Spec.ToRepoCredentials, token)
+	}
+
 	if strings.EqualFold(authMethod, "bearer") {
 		return &http.TokenAuth{
 			Token: token,
@@ -135,3 +185,25 @@ func getToRepoCredentials(ctx context.Context, k client.Client, pc *v1alpha1.Pro
 		Password: token,
 	}, nil
 }
+
+// getTimeouts returns the configured operation timeouts.
+func getTimeouts(spec *v1alpha1.Timeouts) Timeouts {
+	if spec == nil {
+		return Timeouts{}
+	}
+
+	duration := func(d *metav1.Duration) time.Duration {
+		if d == nil {
+			return 0
+		}
+		return d.Duration
+	}
+
+	return Timeouts{
+		Clone:      duration(spec.Clone),
+		Fetch:      duration(spec.Fetch),
+		Push:       duration(spec.Push),
+		Deployment: duration(spec.Deployment),
+		Hosting:    duration(spec.Hosting),
+	}
+}
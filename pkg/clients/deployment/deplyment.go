@@ -2,19 +2,21 @@ package deployment
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/carlmjohnson/requests"
 	"github.com/ghodss/yaml"
 )
 
-func Get(serviceUrl, deploymentId string) ([]byte, error) {
+func Get(ctx context.Context, cl *http.Client, serviceUrl, deploymentId string) ([]byte, error) {
 	tmp := map[string]any{}
 
 	err := requests.
 		URL(serviceUrl).Path(deploymentId).
+		Client(cl).
 		ToJSON(&tmp).
 		CheckStatus(200).
-		Fetch(context.Background())
+		Fetch(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,547 @@
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// Cache is an on-disk cache of bare repositories keyed by normalized URL.
+// Every clone fetches only the objects missing from the cached repository
+// and then copies the requested commit snapshot in memory.
+//
+// The repositories are locked only within the process: the cache directory
+// must not be shared among replicas.
+type Cache struct {
+	dir     string
+	maxSize int64
+
+	mu    sync.Mutex
+	locks map[string]*cacheLock
+	repos map[string]*cacheEntry
+	total int64
+}
+
+// cacheLock serializes the operations on a cached repository; it is
+// dropped as soon as nobody holds or waits for it.
+type cacheLock struct {
+	sync.Mutex
+	refs int
+}
+
+// cacheEntry tracks the size and the last use of a cached repository.
+type cacheEntry struct {
+	size int64
+	used time.Time
+}
+
+// NewCache returns a cache rooted at dir. When the cache grows beyond
+// maxSize bytes the least recently used repositories are evicted
+// (a maxSize of zero disables the eviction).
+func NewCache(dir string, maxSize int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	// the cached repositories are not local repositories
+	if err := denyLocalRepos(dir); err != nil {
+		return nil, err
+	}
+
+	res := &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+		locks:   map[string]*cacheLock{},
+		repos:   map[string]*cacheEntry{},
+	}
+
+	// the repositories left by a previous run (i.e. on a PVC)
+	all, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, el := range all {
+		if !el.IsDir() {
+			continue
+		}
+
+		fi, err := el.Info()
+		if err != nil {
+			continue
+		}
+
+		size := dirSize(filepath.Join(dir, el.Name()))
+		res.repos[el.Name()] = &cacheEntry{size: size, used: fi.ModTime()}
+		res.total += size
+	}
+
+	return res, nil
+}
+
+// clone fetches the reference (or the commit, if hash is not zero) in the
+// cached repository and returns an in-memory repository holding only its tip.
+func (c *Cache) clone(ctx context.Context, opts *CloneOpts, refName plumbing.ReferenceName, hash plumbing.Hash) (*Repo, error) {
+	specs := []config.RefSpec{
+		"+refs/heads/*:refs/heads/*",
+		"+refs/tags/*:refs/tags/*",
+	}
+	if hash.IsZero() {
+		var err error
+		refName, err = c.concreteRef(ctx, opts, refName)
+		if err != nil {
+			return nil, err
+		}
+
+		specs = []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("+%s:%s", refName, refName)),
+		}
+	}
+
+	res := &Repo{
+		rawURL:     opts.URL,
+		auth:       endpointAuth(opts.URL, opts.Auth),
+		httpClient: opts.HTTPClient,
+		signer:     opts.Signer,
+		storer:     memory.NewStorage(),
+		fs:         memfs.New(),
+	}
+
+	err := c.fetch(ctx, opts, specs, func(cached *git.Repository) error {
+		tip := hash
+		if tip.IsZero() {
+			ref, err := cached.Reference(refName, true)
+			if err != nil {
+				return err
+			}
+			tip = ref.Hash()
+		}
+
+		commit, err := peelToCommit(cached, tip)
+		if err != nil {
+			return err
+		}
+		hash = commit.Hash
+
+		if tip != commit.Hash {
+			// annotated tag
+			if err := copyObject(cached.Storer, res.storer, tip); err != nil {
+				return err
+			}
+		}
+
+		return copyCommit(cached.Storer, res.storer, commit)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res.repo, err = git.Init(res.storer, res.fs)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = res.repo.CreateRemote(&config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{opts.URL},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := res.storer.SetShallow([]plumbing.Hash{hash}); err != nil {
+		return nil, err
+	}
+
+	checkout := &git.CheckoutOptions{Force: true}
+	if refName.IsBranch() {
+		refs := []*plumbing.Reference{
+			plumbing.NewHashReference(refName, hash),
+			plumbing.NewHashReference(plumbing.NewRemoteReferenceName("origin", refName.Short()), hash),
+			plumbing.NewSymbolicReference(plumbing.HEAD, refName),
+		}
+		for _, ref := range refs {
+			if err := res.storer.SetReference(ref); err != nil {
+				return nil, err
+			}
+		}
+		checkout.Branch = refName
+	} else {
+		checkout.Hash = hash
+	}
+
+	wt, err := res.repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := wt.Checkout(checkout); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// hasFile fetches the branch in the cached repository and looks for the file
+// in the tree of its tip commit.
+func (c *Cache) hasFile(ctx context.Context, opts *CloneOpts, branch, path string) (bool, error) {
+	refName := plumbing.NewBranchReferenceName(branch)
+	specs := []config.RefSpec{
+		config.RefSpec(fmt.Sprintf("+%s:%s", refName, refName)),
+	}
+
+	var found bool
+	err := c.fetch(ctx, opts, specs, func(cached *git.Repository) error {
+		ref, err := cached.Reference(refName, true)
+		if err != nil {
+			return err
+		}
+
+		commit, err := cached.CommitObject(ref.Hash())
+		if err != nil {
+			return err
+		}
+
+		_, err = commit.File(path)
+		if err != nil {
+			if errors.Is(err, object.ErrFileNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		found = true
+		return nil
+	})
+
+	return found, err
+}
+
+// concreteRef resolves the remote HEAD to the branch it points to.
+func (c *Cache) concreteRef(ctx context.Context, opts *CloneOpts, refName plumbing.ReferenceName) (plumbing.ReferenceName, error) {
+	if refName != plumbing.HEAD {
+		return refName, nil
+	}
+
+	refs, err := listRefs(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+
+	branch := defaultBranch(refs)
+	if len(branch) == 0 {
+		return "", fmt.Errorf("%w: HEAD", ErrReferenceNotFound)
+	}
+
+	return plumbing.NewBranchReferenceName(branch), nil
+}
+
+// fetch updates the cached repository with the given refspecs and calls fn
+// holding the repository lock. The cached repository keeps the full history
+// (go-git cannot negotiate incremental fetches on shallow repositories), so
+// after the first time only the new objects are downloaded.
+func (c *Cache) fetch(ctx context.Context, opts *CloneOpts, specs []config.RefSpec, fn func(*git.Repository) error) error {
+	key, err := cacheKey(opts.URL)
+	if err != nil {
+		return err
+	}
+
+	lock := c.acquire(key)
+
+	err = func() error {
+		defer c.release(key, lock)
+
+		dir := filepath.Join(c.dir, key)
+
+		cached, err := git.PlainOpen(dir)
+		if errors.Is(err, git.ErrRepositoryNotExists) {
+			cached, err = git.PlainInit(dir, true)
+		}
+		if err != nil {
+			return err
+		}
+
+		rem := git.NewRemote(cached.Storer, &config.RemoteConfig{
+			Name: "origin",
+			URLs: []string{opts.URL},
+		})
+
+		err = rem.FetchContext(withHTTPClient(ctx, opts.HTTPClient), &git.FetchOptions{
+			RemoteName:      "origin",
+			RefSpecs:        specs,
+			Auth:            endpointAuth(opts.URL, opts.Auth),
+			InsecureSkipTLS: skipTLS(opts.Insecure, opts.HTTPClient),
+			Tags:            git.NoTags,
+		})
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return mapError(err)
+		}
+
+		now := time.Now()
+		if err := os.Chtimes(dir, now, now); err != nil {
+			return err
+		}
+
+		c.track(key, dir, now, err == nil)
+
+		return fn(cached)
+	}()
+	if err != nil {
+		return err
+	}
+
+	c.evict()
+
+	return nil
+}
+
+// acquire locks the cached repository.
+func (c *Cache) acquire(key string) *cacheLock {
+	c.mu.Lock()
+	res, ok := c.locks[key]
+	if !ok {
+		res = &cacheLock{}
+		c.locks[key] = res
+	}
+	res.refs++
+	c.mu.Unlock()
+
+	res.Lock()
+
+	return res
+}
+
+// release unlocks the cached repository.
+func (c *Cache) release(key string, lock *cacheLock) {
+	lock.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lock.refs--
+	if lock.refs == 0 {
+		delete(c.locks, key)
+	}
+}
+
+// track records the use of the cached repository, measuring its size
+// again only if the fetch downloaded new objects (or it is new).
+func (c *Cache) track(key, dir string, used time.Time, fetched bool) {
+	c.mu.Lock()
+	entry, ok := c.repos[key]
+	c.mu.Unlock()
+
+	var size int64
+	if fetched || !ok {
+		size = dirSize(dir)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !ok {
+		entry = &cacheEntry{}
+		c.repos[key] = entry
+	}
+	if fetched || !ok {
+		c.total += size - entry.size
+		entry.size = size
+	}
+	entry.used = used
+}
+
+// evict removes the least recently used repositories until the cache size
+// fits maxSize. Repositories in use are never removed.
+func (c *Cache) evict() {
+	if c.maxSize <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	if c.total <= c.maxSize {
+		c.mu.Unlock()
+		return
+	}
+
+	keys := make([]string, 0, len(c.repos))
+	for key := range c.repos {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.repos[keys[i]].used.Before(c.repos[keys[j]].used)
+	})
+
+	// the evicted repositories are locked until removed
+	evicted := map[string]*cacheLock{}
+	total := c.total
+	for _, key := range keys {
+		if total <= c.maxSize {
+			break
+		}
+		if _, busy := c.locks[key]; busy {
+			continue
+		}
+
+		lock := &cacheLock{refs: 1}
+		lock.Lock()
+		c.locks[key] = lock
+
+		evicted[key] = lock
+		total -= c.repos[key].size
+	}
+	c.mu.Unlock()
+
+	for key, lock := range evicted {
+		if err := os.RemoveAll(filepath.Join(c.dir, key)); err == nil {
+			c.mu.Lock()
+			c.total -= c.repos[key].size
+			delete(c.repos, key)
+			c.mu.Unlock()
+		}
+
+		c.release(key, lock)
+	}
+}
+
+func dirSize(dir string) int64 {
+	var res int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if !d.IsDir() {
+			if fi, err := d.Info(); err == nil {
+				res += fi.Size()
+			}
+		}
+		return nil
+	})
+
+	return res
+}
+
+// cacheKey returns a directory name unique for the normalized repository URL.
+func cacheKey(repoUrl string) (string, error) {
+	ep, err := transport.NewEndpoint(repoUrl)
+	if err != nil {
+		return "", err
+	}
+
+	host := strings.ToLower(ep.Host)
+	if ep.Port > 0 {
+		host = fmt.Sprintf("%s:%d", host, ep.Port)
+	}
+
+	path := strings.TrimSuffix(strings.TrimSuffix(ep.Path, "/"), ".git")
+	path = "/" + strings.TrimPrefix(path, "/")
+
+	sum := sha256.Sum256([]byte(ep.Protocol + "://" + host + path))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func peelToCommit(repo *git.Repository, hash plumbing.Hash) (*object.Commit, error) {
+	tag, err := repo.TagObject(hash)
+	if err == nil {
+		return tag.Commit()
+	}
+
+	if !errors.Is(err, plumbing.ErrObjectNotFound) {
+		return nil, err
+	}
+
+	return repo.CommitObject(hash)
+}
+
+// copyCommit copies the commit and its whole tree between storers.
+func copyCommit(from, to storer.EncodedObjectStorer, commit *object.Commit) error {
+	if err := copyObject(from, to, commit.Hash); err != nil {
+		return err
+	}
+
+	return copyTree(from, to, commit.TreeHash)
+}
+
+func copyTree(from, to storer.EncodedObjectStorer, hash plumbing.Hash) error {
+	if to.HasEncodedObject(hash) == nil {
+		return nil
+	}
+
+	tree, err := object.GetTree(from, hash)
+	if err != nil {
+		return err
+	}
+
+	if err := copyObject(from, to, hash); err != nil {
+		return err
+	}
+
+	for _, el := range tree.Entries {
+		switch el.Mode {
+		case filemode.Dir:
+			err = copyTree(from, to, el.Hash)
+		case filemode.Submodule:
+			continue
+		default:
+			err = copyObject(from, to, el.Hash)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyObject(from, to storer.EncodedObjectStorer, hash plumbing.Hash) error {
+	src, err := from.EncodedObject(plumbing.AnyObject, hash)
+	if err != nil {
+		return err
+	}
+
+	in, err := src.Reader()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	dst := to.NewEncodedObject()
+	dst.SetType(src.Type())
+	dst.SetSize(src.Size())
+
+	out, err := dst.Writer()
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	_, err = to.SetEncodedObject(dst)
+	return err
+}
@@ -0,0 +1,125 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheFetchesNewCommits(t *testing.T) {
+	ctx := context.Background()
+
+	dir, hash := newTestRemote(t)
+
+	cache, err := NewCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &CloneOpts{URL: dir, Ref: "main", Shallow: true, Cache: cache}
+	repo, err := Clone(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := repo.FS().Create("CHANGELOG.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("none"))
+	f.Close()
+
+	commitId, err := repo.Commit(".", &CommitOpts{Message: "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Push(ctx, &PushOpts{RemoteName: "origin", Branch: "main"}); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err = Clone(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := repo.HeadCommitId(); got != commitId {
+		t.Fatalf("got commit %s, want %s", got, commitId)
+	}
+
+	// the older commits are still served from the cached repository
+	repo, err = Clone(ctx, &CloneOpts{URL: dir, Ref: hash.String(), Shallow: true, Cache: cache})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := repo.Exists("CHANGELOG.md"); ok {
+		t.Fatalf("unexpected CHANGELOG.md at commit %s", hash)
+	}
+
+	for _, el := range []string{"README.md", "CHANGELOG.md", "MISSING.md"} {
+		got, err := cache.hasFile(ctx, opts, "main", el)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := el != "MISSING.md"; got != want {
+			t.Errorf("hasFile(%s): got %t, want %t", el, got, want)
+		}
+	}
+
+	if n := len(cache.locks); n != 0 {
+		t.Fatalf("got %d repository locks, want none", n)
+	}
+}
+
+func TestCacheEvict(t *testing.T) {
+	ctx := context.Background()
+
+	first, _ := newTestRemote(t)
+	second, _ := newTestRemote(t)
+
+	cacheDir := t.TempDir()
+	cache, err := NewCache(cacheDir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Clone(ctx, &CloneOpts{URL: first, Shallow: true, Cache: cache}); err != nil {
+		t.Fatal(err)
+	}
+
+	firstKey, _ := cacheKey(first)
+	size := cache.repos[firstKey].size
+	if size == 0 || size != cache.total {
+		t.Fatalf("got size %d and total %d, want the same positive size", size, cache.total)
+	}
+
+	// room for just one of the (almost equal) repositories
+	cache.maxSize = size * 3 / 2
+
+	if _, err := Clone(ctx, &CloneOpts{URL: second, Shallow: true, Cache: cache}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(cacheDir, firstKey)); !os.IsNotExist(err) {
+		t.Fatalf("expected the least recently used repository to be evicted: %v", err)
+	}
+
+	secondKey, _ := cacheKey(second)
+	if _, err := os.Stat(filepath.Join(cacheDir, secondKey)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.repos[firstKey]; ok || len(cache.repos) != 1 || cache.total != cache.repos[secondKey].size {
+		t.Fatalf("unexpected tracked repositories: %d (total: %d)", len(cache.repos), cache.total)
+	}
+	if n := len(cache.locks); n != 0 {
+		t.Fatalf("got %d repository locks, want none", n)
+	}
+
+	// the sizes of the repositories left on disk are measured again
+	reopened, err := NewCache(cacheDir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.total != cache.total {
+		t.Fatalf("got total %d, want %d", reopened.total, cache.total)
+	}
+}
@@ -0,0 +1,255 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// isNonFastForward tells if the push was rejected because the remote branch
+// moved. On shallow repositories go-git cannot walk the history to tell and
+// fails on a missing object: the branch moved only if the missing object is
+// the advertised remote tip.
+func (s *Repo) isNonFastForward(ctx context.Context, remoteName string, refName plumbing.ReferenceName, insecure bool, err error) bool {
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		tip, err := s.remoteTip(ctx, remoteName, refName, insecure)
+		if err != nil || tip.IsZero() {
+			return false
+		}
+
+		return s.storer.HasEncodedObject(tip) != nil
+	}
+
+	msg := err.Error()
+	return strings.Contains(msg, "non-fast-forward") ||
+		strings.Contains(msg, "fetch first")
+}
+
+// remoteTip returns the hash advertised by the remote for the branch, zero
+// if the branch is missing.
+func (s *Repo) remoteTip(ctx context.Context, remoteName string, refName plumbing.ReferenceName, insecure bool) (plumbing.Hash, error) {
+	remote, err := s.repo.Remote(remoteName)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	refs, err := remote.ListContext(withHTTPClient(ctx, s.httpClient), &git.ListOptions{
+		Auth:            s.auth,
+		InsecureSkipTLS: skipTLS(insecure, s.httpClient),
+	})
+	if err != nil {
+		return plumbing.ZeroHash, mapError(err)
+	}
+
+	for _, ref := range refs {
+		if ref.Name() == refName {
+			return ref.Hash(), nil
+		}
+	}
+
+	return plumbing.ZeroHash, nil
+}
+
+// replay recreates the HEAD commit on top of the current remote branch tip,
+// as 'git pull --rebase' would do. The paths changed by the commit must not
+// have been changed differently on the remote branch.
+func (s *Repo) replay(ctx context.Context, remoteName string, refName plumbing.ReferenceName, insecure bool) error {
+	head, err := s.repo.Head()
+	if err != nil {
+		return err
+	}
+
+	ours, err := s.repo.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+
+	var base *object.Tree
+	switch ours.NumParents() {
+	case 0:
+	case 1:
+		parent, err := ours.Parent(0)
+		if err != nil {
+			return err
+		}
+		if base, err = parent.Tree(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("cannot replay merge commit %s", ours.Hash)
+	}
+
+	oursTree, err := ours.Tree()
+	if err != nil {
+		return err
+	}
+
+	changes, err := object.DiffTree(base, oursTree)
+	if err != nil {
+		return err
+	}
+
+	tip, err := s.fetchTip(ctx, remoteName, refName, insecure)
+	if err != nil {
+		return err
+	}
+
+	theirsTree, err := tip.Tree()
+	if err != nil {
+		return err
+	}
+
+	for _, el := range changes {
+		path := el.To.Name
+		if len(path) == 0 {
+			path = el.From.Name
+		}
+
+		var theirs object.TreeEntry
+		if entry, err := theirsTree.FindEntry(path); err == nil {
+			theirs = *entry
+		}
+
+		if !sameEntry(theirs, el.From.TreeEntry) && !sameEntry(theirs, el.To.TreeEntry) {
+			return fmt.Errorf("%w: %s", ErrReplayConflict, path)
+		}
+	}
+
+	err = s.repo.Storer.SetReference(plumbing.NewHashReference(refName, tip.Hash))
+	if err != nil {
+		return err
+	}
+
+	wt, err := s.repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	err = wt.Checkout(&git.CheckoutOptions{Branch: refName, Force: true})
+	if err != nil {
+		return err
+	}
+
+	idx, err := s.repo.Storer.Index()
+	if err != nil {
+		return err
+	}
+
+	for _, el := range changes {
+		path := el.To.Name
+		if len(path) == 0 {
+			path = el.From.Name
+		}
+
+		if len(el.To.Name) == 0 {
+			if _, err := idx.Remove(path); err != nil && !errors.Is(err, index.ErrEntryNotFound) {
+				return err
+			}
+			continue
+		}
+
+		entry, err := idx.Entry(path)
+		if err != nil {
+			entry = idx.Add(path)
+		}
+		entry.Hash = el.To.TreeEntry.Hash
+		entry.Mode = el.To.TreeEntry.Mode
+		entry.ModifiedAt = time.Now()
+	}
+
+	if err := s.repo.Storer.SetIndex(idx); err != nil {
+		return err
+	}
+
+	committer := ours.Committer
+	committer.When = time.Now()
+
+	hash, err := wt.Commit(ours.Message, &git.CommitOptions{
+		Author:    &ours.Author,
+		Committer: &committer,
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.signer != nil {
+		if _, err := s.signHead(hash); err != nil {
+			return err
+		}
+	}
+
+	// aligns the worktree to the index
+	return wt.Checkout(&git.CheckoutOptions{Branch: refName, Force: true})
+}
+
+// fetchTip fetches the tip commit of the remote branch and copies it in the
+// repository as a new shallow commit (go-git cannot fetch incrementally
+// into shallow repositories).
+func (s *Repo) fetchTip(ctx context.Context, remoteName string, refName plumbing.ReferenceName, insecure bool) (*object.Commit, error) {
+	remote, err := s.repo.Remote(remoteName)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteRef := plumbing.NewRemoteReferenceName(remoteName, refName.Short())
+
+	st := memory.NewStorage()
+	rem := git.NewRemote(st, &config.RemoteConfig{
+		Name: remoteName,
+		URLs: remote.Config().URLs,
+	})
+
+	err = rem.FetchContext(withHTTPClient(ctx, s.httpClient), &git.FetchOptions{
+		RemoteName:      remoteName,
+		RefSpecs:        []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", refName, remoteRef))},
+		Auth:            s.auth,
+		InsecureSkipTLS: skipTLS(insecure, s.httpClient),
+		Depth:           shallowDepth(remote.Config().URLs[0]),
+		Tags:            git.NoTags,
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	ref, err := st.Reference(remoteRef)
+	if err != nil {
+		return nil, err
+	}
+
+	tip, err := object.GetCommit(st, ref.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := copyCommit(st, s.storer, tip); err != nil {
+		return nil, err
+	}
+
+	shallows, err := s.storer.Shallow()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.storer.SetShallow(append(shallows, tip.Hash)); err != nil {
+		return nil, err
+	}
+
+	if err := s.storer.SetReference(plumbing.NewHashReference(remoteRef, tip.Hash)); err != nil {
+		return nil, err
+	}
+
+	return s.repo.CommitObject(tip.Hash)
+}
+
+func sameEntry(a, b object.TreeEntry) bool {
+	return a.Hash == b.Hash && a.Mode == b.Mode
+}
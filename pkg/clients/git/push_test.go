@@ -0,0 +1,220 @@
+package git
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+// commitFile writes the file and commits it.
+func commitFile(t *testing.T, repo *Repo, name, content string) string {
+	t.Helper()
+
+	f, err := repo.FS().Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(content))
+	f.Close()
+
+	res, err := repo.Commit(".", &CommitOpts{Message: "update " + name})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+// remoteTipCommit returns the tip commit of the remote 'main' branch.
+func remoteTipCommit(t *testing.T, dir string) *object.Commit {
+	t.Helper()
+
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref, err := r.Reference("refs/heads/main", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := r.CommitObject(ref.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+// movedRemote returns a clone of the remote 'main' branch made before another
+// one pushed the file to it; the pushed commit id is returned as well.
+func movedRemote(t *testing.T, dir string, opts *CloneOpts, name, content string) (*Repo, string) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	opts.URL, opts.Ref = dir, "main"
+	res, err := Clone(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := Clone(ctx, &CloneOpts{URL: dir, Ref: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commitId := commitFile(t, other, name, content)
+	if err := other.Push(ctx, &PushOpts{RemoteName: "origin", Branch: "main"}); err != nil {
+		t.Fatal(err)
+	}
+
+	return res, commitId
+}
+
+func TestPushReplay(t *testing.T) {
+	ctx := context.Background()
+
+	dir, _ := newTestRemote(t)
+
+	repo, theirs := movedRemote(t, dir, &CloneOpts{}, "CHANGELOG.md", "none")
+	commitFile(t, repo, "NOTES.md", "notes")
+
+	err := repo.Push(ctx, &PushOpts{RemoteName: "origin", Branch: "main"})
+	if !errors.Is(err, ErrNonFastForward) {
+		t.Fatalf("got %v, want %v", err, ErrNonFastForward)
+	}
+
+	if err := repo.Push(ctx, &PushOpts{RemoteName: "origin", Branch: "main", Retries: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	tip := remoteTipCommit(t, dir)
+	if tip.NumParents() != 1 || tip.ParentHashes[0].String() != theirs {
+		t.Fatalf("expected the commit to be replayed on %s, got parents %v", theirs, tip.ParentHashes)
+	}
+	for _, el := range []string{"README.md", "CHANGELOG.md", "NOTES.md"} {
+		if _, err := tip.File(el); err != nil {
+			t.Errorf("%s: %v", el, err)
+		}
+	}
+
+	if got, _ := repo.HeadCommitId(); got != tip.Hash.String() {
+		t.Fatalf("got HEAD %s, want the pushed commit %s", got, tip.Hash)
+	}
+}
+
+func TestPushReplayConflict(t *testing.T) {
+	ctx := context.Background()
+
+	dir, _ := newTestRemote(t)
+
+	repo, theirs := movedRemote(t, dir, &CloneOpts{}, "README.md", "theirs")
+	commitFile(t, repo, "README.md", "ours")
+
+	err := repo.Push(ctx, &PushOpts{RemoteName: "origin", Branch: "main", Retries: 1})
+	if !errors.Is(err, ErrReplayConflict) {
+		t.Fatalf("got %v, want %v", err, ErrReplayConflict)
+	}
+
+	if tip := remoteTipCommit(t, dir); tip.Hash.String() != theirs {
+		t.Fatalf("got remote tip %s, want %s", tip.Hash, theirs)
+	}
+}
+
+func TestPushForce(t *testing.T) {
+	ctx := context.Background()
+
+	dir, _ := newTestRemote(t)
+
+	repo, _ := movedRemote(t, dir, &CloneOpts{}, "README.md", "theirs")
+	ours := commitFile(t, repo, "README.md", "ours")
+
+	if err := repo.Push(ctx, &PushOpts{RemoteName: "origin", Branch: "main", Force: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if tip := remoteTipCommit(t, dir); tip.Hash.String() != ours {
+		t.Fatalf("got remote tip %s, want %s", tip.Hash, ours)
+	}
+}
+
+func TestPushReplaySigned(t *testing.T) {
+	ctx := context.Background()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshKey, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, _ := newTestRemote(t)
+
+	repo, _ := movedRemote(t, dir, &CloneOpts{Signer: &sshSigner{key: sshKey}}, "CHANGELOG.md", "none")
+	commitFile(t, repo, "NOTES.md", "notes")
+
+	if err := repo.Push(ctx, &PushOpts{RemoteName: "origin", Branch: "main", Retries: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	tip := remoteTipCommit(t, dir)
+	if len(tip.PGPSignature) == 0 {
+		t.Fatalf("expected the replayed commit to be signed")
+	}
+
+	payload := &plumbing.MemoryObject{}
+	if err := tip.EncodeWithoutSignature(payload); err != nil {
+		t.Fatal(err)
+	}
+	verifySSHSignature(t, sshKey.PublicKey(), readObject(t, payload), tip.PGPSignature)
+}
+
+func TestIsNonFastForward(t *testing.T) {
+	ctx := context.Background()
+
+	dir, _ := newTestRemote(t)
+
+	repo, err := Clone(ctx, &CloneOpts{URL: dir, Ref: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the advertised tip is known: something else is missing
+	if repo.isNonFastForward(ctx, "origin", "refs/heads/main", false, plumbing.ErrObjectNotFound) {
+		t.Fatalf("expected a missing object other than the remote tip not to be a non-fast-forward")
+	}
+
+	repo, _ = movedRemote(t, dir, &CloneOpts{}, "CHANGELOG.md", "none")
+
+	table := []struct {
+		err  error
+		want bool
+	}{
+		{plumbing.ErrObjectNotFound, true},
+		{errors.New("non-fast-forward update: refs/heads/main"), true},
+		{errors.New("! [rejected] main -> main (fetch first)"), true},
+		{errors.New("unexpected EOF"), false},
+	}
+
+	for _, tc := range table {
+		if got := repo.isNonFastForward(ctx, "origin", "refs/heads/main", false, tc.err); got != tc.want {
+			t.Errorf("%v: got %t, want %t", tc.err, got, tc.want)
+		}
+	}
+
+	// a missing branch has no tip to miss
+	if repo.isNonFastForward(ctx, "origin", "refs/heads/missing", false, plumbing.ErrObjectNotFound) {
+		t.Fatalf("expected a missing remote branch not to be a non-fast-forward")
+	}
+}
@@ -0,0 +1,115 @@
+package git
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// BranchTip returns the tip commit SHA of the named remote branch listing the
+// remote references only; if name is empty the remote HEAD branch is used.
+// The returned SHA is empty if the branch (or the whole repository) is empty.
+func BranchTip(ctx context.Context, opts *CloneOpts, name string) (branch string, commitId string, err error) {
+	refs, err := listRefs(ctx, opts)
+	if err != nil {
+		if errors.Is(err, ErrEmptyRemoteRepository) {
+			return name, "", nil
+		}
+		return "", "", err
+	}
+
+	if len(name) == 0 {
+		name = defaultBranch(refs)
+	}
+
+	want := plumbing.NewBranchReferenceName(name)
+	for _, ref := range refs {
+		if ref.Name() == want {
+			return name, ref.Hash().String(), nil
+		}
+	}
+
+	return name, "", nil
+}
+
+// HasFile tells if the file exists in the tip commit of the remote branch.
+// Since partial clones are not supported, the tip commit snapshot is fetched
+// in a bare in-memory repository and no worktree is checked out.
+func HasFile(ctx context.Context, opts *CloneOpts, branch, path string) (bool, error) {
+	if opts.Cache != nil {
+		return opts.Cache.hasFile(ctx, opts, branch, path)
+	}
+
+	repo, err := git.CloneContext(withHTTPClient(ctx, opts.HTTPClient), memory.NewStorage(), nil, &git.CloneOptions{
+		RemoteName:      "origin",
+		URL:             opts.URL,
+		Auth:            endpointAuth(opts.URL, opts.Auth),
+		InsecureSkipTLS: skipTLS(opts.Insecure, opts.HTTPClient),
+		ReferenceName:   plumbing.NewBranchReferenceName(branch),
+		SingleBranch:    true,
+		Depth:           shallowDepth(opts.URL),
+		Tags:            git.NoTags,
+	})
+	if err != nil {
+		return false, mapError(err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return false, err
+	}
+
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return false, err
+	}
+
+	_, err = commit.File(path)
+	if err != nil {
+		if errors.Is(err, object.ErrFileNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// defaultBranch returns the name of the branch pointed by the remote HEAD.
+func defaultBranch(refs []*plumbing.Reference) string {
+	var head *plumbing.Reference
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD {
+			head = ref
+			break
+		}
+	}
+
+	if head == nil {
+		return ""
+	}
+
+	if head.Type() == plumbing.SymbolicReference {
+		// a bare repository HEAD may point to a branch never pushed
+		for _, ref := range refs {
+			if ref.Name() == head.Target() {
+				return head.Target().Short()
+			}
+		}
+		return ""
+	}
+
+	// the server did not advertise the HEAD symref:
+	// look for a branch pointing to the same commit
+	for _, ref := range refs {
+		if ref.Name().IsBranch() && ref.Hash() == head.Hash() {
+			return ref.Name().Short()
+		}
+	}
+
+	return ""
+}
@@ -1,9 +1,17 @@
 package git
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/go-git/go-billy/v5"
@@ -13,7 +21,7 @@ import (
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/transport"
-	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/go-git/go-git/v5/storage/memory"
 )
 
@@ -27,29 +35,55 @@ var (
 	ErrEmptyRemoteRepository  = errors.New("remote repository is empty")
 	ErrAuthenticationRequired = errors.New("authentication required")
 	ErrAuthorizationFailed    = errors.New("authorization failed")
+	ErrReferenceNotFound      = errors.New("reference not found")
+	ErrNonFastForward         = errors.New("non-fast-forward update rejected")
+	ErrReplayConflict         = errors.New("conflicting changes on the remote branch")
+	ErrTLS                    = errors.New("tls handshake failed")
+	ErrTimeout                = errors.New("network timeout")
+	ErrProtectedBranch        = errors.New("protected branch update rejected")
+	ErrTooLarge               = errors.New("push too large")
+	ErrLocalRepoNotAllowed    = errors.New("local repository not allowed")
 )
 
 // Repo is an in-memory git repository
 type Repo struct {
-	rawURL string
-	auth   transport.AuthMethod
-	storer *memory.Storage
-	fs     billy.Filesystem
-	repo   *git.Repository
+	rawURL     string
+	auth       transport.AuthMethod
+	httpClient *http.Client
+	signer     Signer
+	storer     *memory.Storage
+	fs         billy.Filesystem
+	repo       *git.Repository
 }
 
-func Tags(repoUrl string, auth transport.AuthMethod, insecure bool) ([]string, error) {
-	// Create the remote with repository URL
-	rem := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
-		Name: "origin",
-		URLs: []string{repoUrl},
-	})
+// CloneOpts describes how a repository should be cloned.
+type CloneOpts struct {
+	// URL: the repository URL.
+	URL string
+	// Auth: credentials, if required, to use with the remote repository.
+	Auth transport.AuthMethod
+	// Insecure: skips the TLS certificates verification.
+	Insecure bool
+	// HTTPClient: the client used with http(s) remotes; its TLS configuration
+	// replaces Insecure. Proxy settings must be configured here too.
+	HTTPClient *http.Client
+	// Ref: the branch, tag or full commit SHA to checkout (default: the remote HEAD).
+	Ref string
+	// Shallow: fetch only the tip commit of the requested branch or tag.
+	// Ignored when Ref is a commit SHA since it may not be a branch or tag tip.
+	Shallow bool
+	// Signer: signs the commits and the annotated tags, if any.
+	Signer Signer
+	// Cache: fetch through this on-disk cache, if any. The returned
+	// repository holds only the requested commit, as with Shallow.
+	Cache *Cache
+	// RecurseSubmodules: checks out the submodules too, recursively,
+	// fetching them with the same credentials.
+	RecurseSubmodules bool
+}
 
-	// We can then use every Remote functions to retrieve wanted information
-	refs, err := rem.List(&git.ListOptions{
-		Auth:            auth,
-		InsecureSkipTLS: insecure,
-	})
+func Tags(ctx context.Context, opts *CloneOpts) ([]string, error) {
+	refs, err := listRefs(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -65,50 +99,289 @@ func Tags(repoUrl string, auth transport.AuthMethod, insecure bool) ([]string, e
 	return tags, nil
 }
 
-func Clone(repoUrl string, auth transport.AuthMethod, insecure bool) (*Repo, error) {
+func Clone(ctx context.Context, opts *CloneOpts) (*Repo, error) {
+	res, err := clone(ctx, opts)
+	if err != nil || !opts.RecurseSubmodules {
+		return res, err
+	}
+
+	if err := updateSubmodules(ctx, res.repo, opts); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func clone(ctx context.Context, opts *CloneOpts) (*Repo, error) {
+	refName, hash, err := resolveRef(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Cache != nil {
+		return opts.Cache.clone(ctx, opts, refName, hash)
+	}
+
 	res := &Repo{
-		rawURL: repoUrl,
-		auth:   auth,
-		storer: memory.NewStorage(),
-		fs:     memfs.New(),
+		rawURL:     opts.URL,
+		auth:       endpointAuth(opts.URL, opts.Auth),
+		httpClient: opts.HTTPClient,
+		signer:     opts.Signer,
+		storer:     memory.NewStorage(),
+		fs:         memfs.New(),
+	}
+
+	cloneOpts := &git.CloneOptions{
+		RemoteName:      "origin",
+		URL:             opts.URL,
+		Auth:            endpointAuth(opts.URL, opts.Auth),
+		InsecureSkipTLS: skipTLS(opts.Insecure, opts.HTTPClient),
+		ReferenceName:   refName,
+	}
+	if opts.Shallow && hash.IsZero() {
+		cloneOpts.Depth = shallowDepth(opts.URL)
+		cloneOpts.SingleBranch = true
+		cloneOpts.Tags = git.NoTags
 	}
 
 	// Clone the given repository to the given directory
+	res.repo, err = git.CloneContext(withHTTPClient(ctx, opts.HTTPClient), res.storer, res.fs, cloneOpts)
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	if !hash.IsZero() {
+		if _, err := res.repo.CommitObject(hash); err != nil {
+			return nil, fmt.Errorf("commit %s: %w", hash, err)
+		}
+
+		wt, err := res.repo.Worktree()
+		if err != nil {
+			return nil, err
+		}
+
+		err = wt.Checkout(&git.CheckoutOptions{Hash: hash, Force: true})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// Init returns a new in-memory repository, without commits, for an empty
+// remote repository: origin is set to the remote URL and HEAD to the branch.
+func Init(opts *CloneOpts, branch string) (*Repo, error) {
+	res := &Repo{
+		rawURL:     opts.URL,
+		auth:       endpointAuth(opts.URL, opts.Auth),
+		httpClient: opts.HTTPClient,
+		signer:     opts.Signer,
+		storer:     memory.NewStorage(),
+		fs:         memfs.New(),
+	}
+
 	var err error
-	res.repo, err = git.Clone(res.storer, res.fs, &git.CloneOptions{
-		RemoteName:      "origin",
-		URL:             repoUrl,
-		Auth:            auth,
-		InsecureSkipTLS: insecure,
+	res.repo, err = git.Init(res.storer, res.fs)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = res.repo.CreateRemote(&config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{opts.URL},
 	})
 	if err != nil {
-		if errors.Is(err, transport.ErrRepositoryNotFound) {
-			return nil, ErrRepositoryNotFound
+		return nil, err
+	}
+
+	head := plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(branch))
+	if err := res.storer.SetReference(head); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// resolveRef finds out if the requested ref is a remote branch, a remote tag
+// or a commit SHA. Branches and tags are returned as references to clone,
+// commit SHAs as hashes to checkout after cloning the remote HEAD.
+func resolveRef(ctx context.Context, opts *CloneOpts) (plumbing.ReferenceName, plumbing.Hash, error) {
+	if len(opts.Ref) == 0 && !isLocal(opts.URL) {
+		return plumbing.HEAD, plumbing.ZeroHash, nil
+	}
+
+	refs, err := listRefs(ctx, opts)
+	if err != nil {
+		return "", plumbing.ZeroHash, err
+	}
+	if !hasBranchesOrTags(refs) {
+		// the file server advertises just the HEAD instead of failing
+		return "", plumbing.ZeroHash, ErrEmptyRemoteRepository
+	}
+
+	if len(opts.Ref) == 0 {
+		// the file server does not advertise the HEAD symref
+		// and go-git would look for the 'master' branch
+		if branch := defaultBranch(refs); len(branch) > 0 {
+			return plumbing.NewBranchReferenceName(branch), plumbing.ZeroHash, nil
 		}
+		return plumbing.HEAD, plumbing.ZeroHash, nil
+	}
 
-		if errors.Is(err, transport.ErrEmptyRemoteRepository) {
-			return nil, ErrEmptyRemoteRepository
+	candidates := []plumbing.ReferenceName{
+		plumbing.ReferenceName(opts.Ref),
+		plumbing.NewBranchReferenceName(opts.Ref),
+		plumbing.NewTagReferenceName(opts.Ref),
+	}
+	for _, name := range candidates {
+		for _, ref := range refs {
+			if ref.Name() == name && (name.IsBranch() || name.IsTag()) {
+				return name, plumbing.ZeroHash, nil
+			}
 		}
+	}
+
+	if isCommitSHA(opts.Ref) {
+		return plumbing.HEAD, plumbing.NewHash(opts.Ref), nil
+	}
+
+	return "", plumbing.ZeroHash, fmt.Errorf("%w: %s", ErrReferenceNotFound, opts.Ref)
+}
 
-		if errors.Is(err, transport.ErrAuthenticationRequired) {
-			return nil, ErrAuthenticationRequired
+func hasBranchesOrTags(refs []*plumbing.Reference) bool {
+	for _, ref := range refs {
+		if ref.Name().IsBranch() || ref.Name().IsTag() {
+			return true
 		}
+	}
+
+	return false
+}
+
+func isCommitSHA(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// listRefs lists the remote references without cloning the repository.
+func listRefs(ctx context.Context, opts *CloneOpts) ([]*plumbing.Reference, error) {
+	// Create the remote with repository URL
+	rem := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{opts.URL},
+	})
 
-		if errors.Is(err, transport.ErrAuthorizationFailed) {
-			return nil, ErrAuthorizationFailed
+	// We can then use every Remote functions to retrieve wanted information
+	refs, err := rem.ListContext(withHTTPClient(ctx, opts.HTTPClient), &git.ListOptions{
+		Auth:            endpointAuth(opts.URL, opts.Auth),
+		InsecureSkipTLS: skipTLS(opts.Insecure, opts.HTTPClient),
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	return refs, nil
+}
+
+func mapError(err error) error {
+	if errors.Is(err, transport.ErrRepositoryNotFound) {
+		return ErrRepositoryNotFound
+	}
+
+	if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return ErrEmptyRemoteRepository
+	}
+
+	if errors.Is(err, transport.ErrAuthenticationRequired) {
+		return ErrAuthenticationRequired
+	}
+
+	if errors.Is(err, transport.ErrAuthorizationFailed) {
+		return ErrAuthorizationFailed
+	}
+
+	if isTLSError(err) {
+		return fmt.Errorf("%w: %v", ErrTLS, err)
+	}
+
+	if isTimeout(err) {
+		return fmt.Errorf("%w: %v", ErrTimeout, err)
+	}
+
+	var ue *plumbing.UnexpectedError
+	if errors.As(err, &ue) {
+		if he, ok := ue.Err.(*githttp.Err); ok && he.StatusCode() == http.StatusRequestEntityTooLarge {
+			return fmt.Errorf("%w: %v", ErrTooLarge, err)
 		}
+	}
 
-		return nil, err
-		/*
-			h := plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.ReferenceName("refs/heads/main"))
-			err := res.repo.Storer.SetReference(h)
-			if err != nil {
-				return nil, err
-			}
-		*/
+	// the server rejections are reported only as text: match just the
+	// known messages, an unrelated rejection keeps the generic error
+	msg := strings.ToLower(err.Error())
+	for _, el := range serverMessages {
+		if strings.Contains(msg, el.text) {
+			return fmt.Errorf("%w: %v", el.err, err)
+		}
 	}
 
-	return res, nil
+	return err
+}
+
+// serverMessages are the (lowercase) rejection messages of the ssh client
+// and of the git servers and hosting providers.
+var serverMessages = []struct {
+	text string
+	err  error
+}{
+	{"ssh: unable to authenticate", ErrAuthenticationRequired},
+	// GitHub
+	{"protected branch hook declined", ErrProtectedBranch},
+	{"gh006: protected branch update failed", ErrProtectedBranch},
+	{"gh001: large files detected", ErrTooLarge},
+	{"exceeds github's file size limit", ErrTooLarge},
+	// GitLab
+	{"you are not allowed to push code to protected branches", ErrProtectedBranch},
+	{"you are not allowed to force push code to a protected branch", ErrProtectedBranch},
+	// Gitea
+	{"not allowed to push to protected branch", ErrProtectedBranch},
+	// git receive.maxInputSize
+	{"pack exceeds maximum allowed size", ErrTooLarge},
+}
+
+func isTLSError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalidCert x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	var recordHeader tls.RecordHeaderError
+
+	return errors.As(err, &unknownAuthority) ||
+		errors.As(err, &invalidCert) ||
+		errors.As(err, &hostname) ||
+		errors.As(err, &recordHeader)
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// HeadCommitId returns the SHA of the commit pointed by HEAD.
+func (s *Repo) HeadCommitId() (string, error) {
+	ref, err := s.repo.Head()
+	if err != nil {
+		return "", err
+	}
+
+	return ref.Hash().String(), nil
 }
 
 func (s *Repo) Exists(path string) (bool, error) {
@@ -128,14 +401,35 @@ func (s *Repo) FS() billy.Filesystem {
 	return s.fs
 }
 
+// Branch checks out the named branch. If it does not exist locally it is
+// created from the matching remote branch or, as a last resort, from HEAD.
+// In a repository without commits HEAD is just pointed to the branch.
 func (s *Repo) Branch(name string) error {
-	branch := fmt.Sprintf("refs/heads/%s", name)
-	ref := plumbing.ReferenceName(branch)
+	ref := plumbing.NewBranchReferenceName(name)
+
+	if _, err := s.repo.Head(); errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return s.storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, ref))
+	}
 
-	h := plumbing.NewSymbolicReference(plumbing.HEAD, ref)
-	err := s.repo.Storer.SetReference(h)
+	opts := &git.CheckoutOptions{
+		Branch: ref,
+	}
+
+	_, err := s.repo.Reference(ref, true)
 	if err != nil {
-		return err
+		if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return err
+		}
+
+		opts.Create = true
+
+		remote, err := s.repo.Reference(plumbing.NewRemoteReferenceName("origin", name), true)
+		if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return err
+		}
+		if remote != nil {
+			opts.Hash = remote.Hash()
+		}
 	}
 
 	wt, err := s.repo.Worktree()
@@ -143,13 +437,41 @@ func (s *Repo) Branch(name string) error {
 		return err
 	}
 
-	return wt.Checkout(&git.CheckoutOptions{
-		Create: false,
-		Branch: ref,
-	})
+	return wt.Checkout(opts)
 }
 
-func (s *Repo) Commit(path, msg string) (string, error) {
+// CurrentBranch returns the name of the branch pointed by HEAD; right after
+// a clone this is the remote default branch.
+func (s *Repo) CurrentBranch() (string, error) {
+	ref, err := s.repo.Head()
+	if err != nil {
+		return "", err
+	}
+
+	if !ref.Name().IsBranch() {
+		return "", fmt.Errorf("HEAD is not pointing to a branch")
+	}
+
+	return ref.Name().Short(), nil
+}
+
+// Identity is the name and email of a commit author or committer.
+type Identity struct {
+	Name  string
+	Email string
+}
+
+// CommitOpts describes the commit to create.
+type CommitOpts struct {
+	// Message: the commit message.
+	Message string
+	// Author: the commit author (default: krateoctl).
+	Author *Identity
+	// Committer: the commit committer (default: the author).
+	Committer *Identity
+}
+
+func (s *Repo) Commit(path string, opts *CommitOpts) (string, error) {
 	wt, err := s.repo.Worktree()
 	if err != nil {
 		return "", err
@@ -159,29 +481,102 @@ func (s *Repo) Commit(path, msg string) (string, error) {
 		return "", err
 	}
 
+	author := opts.Author
+	if author == nil {
+		author = &Identity{Name: commitAuthorName, Email: commitAuthorEmail}
+	}
+
+	committer := opts.Committer
+	if committer == nil {
+		committer = author
+	}
+
+	now := time.Now()
+
 	// git commit -m $message
-	hash, err := wt.Commit(msg, &git.CommitOptions{
+	hash, err := wt.Commit(opts.Message, &git.CommitOptions{
 		Author: &object.Signature{
-			Name:  commitAuthorName,
-			Email: commitAuthorEmail,
-			When:  time.Now(),
+			Name:  author.Name,
+			Email: author.Email,
+			When:  now,
+		},
+		Committer: &object.Signature{
+			Name:  committer.Name,
+			Email: committer.Email,
+			When:  now,
 		},
 	})
 	if err != nil {
 		return "", err
 	}
 
+	if s.signer != nil {
+		hash, err = s.signHead(hash)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	return hash.String(), nil
 }
 
-func (s *Repo) Push(downstream, branch string, insecure bool) error {
+// signHead replaces the just created commit with its signed copy.
+func (s *Repo) signHead(hash plumbing.Hash) (plumbing.Hash, error) {
+	commit, err := s.repo.CommitObject(hash)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	hash, err = signCommit(s.repo.Storer, s.signer, commit)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	head, err := s.repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	name := plumbing.HEAD
+	if head.Type() == plumbing.SymbolicReference {
+		name = head.Target()
+	}
+
+	err = s.repo.Storer.SetReference(plumbing.NewHashReference(name, hash))
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return hash, nil
+}
+
+// PushOpts describes how a branch is pushed.
+type PushOpts struct {
+	// RemoteName: the remote to push to.
+	RemoteName string
+	// Branch: the branch to push (default: the remote configured refspecs).
+	Branch string
+	// Insecure: skips the TLS certificates verification.
+	Insecure bool
+	// Force: overwrites the remote branch even if the push is not a fast-forward.
+	Force bool
+	// Retries: how many times, after a non-fast-forward rejection, the last
+	// commit is replayed on the new remote branch tip and pushed again.
+	Retries int
+}
+
+func (s *Repo) Push(ctx context.Context, opts *PushOpts) error {
 	//Push the code to the remote
-	if len(branch) == 0 {
-		return s.repo.Push(&git.PushOptions{
-			RemoteName:      downstream,
+	if len(opts.Branch) == 0 {
+		err := s.repo.PushContext(withHTTPClient(ctx, s.httpClient), &git.PushOptions{
+			RemoteName:      opts.RemoteName,
 			Auth:            s.auth,
-			InsecureSkipTLS: insecure,
+			InsecureSkipTLS: skipTLS(opts.Insecure, s.httpClient),
 		})
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return mapError(err)
+		}
+		return nil
 	}
 
 	headRef, err := s.repo.Head()
@@ -189,7 +584,7 @@ func (s *Repo) Push(downstream, branch string, insecure bool) error {
 		return err
 	}
 
-	refName := plumbing.NewBranchReferenceName(branch)
+	refName := plumbing.NewBranchReferenceName(opts.Branch)
 
 	refs, err := s.repo.References()
 	if err != nil {
@@ -212,37 +607,57 @@ func (s *Repo) Push(downstream, branch string, insecure bool) error {
 		}
 	}
 
-	return s.repo.Push(&git.PushOptions{
-		RemoteName:      downstream,
-		Force:           true,
-		Auth:            s.auth,
-		InsecureSkipTLS: insecure,
-		RefSpecs: []config.RefSpec{
-			config.RefSpec(refName + ":" + refName),
-		},
-	})
+	refSpec := config.RefSpec(refName + ":" + refName)
+	if opts.Force {
+		refSpec = "+" + refSpec
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = s.repo.PushContext(withHTTPClient(ctx, s.httpClient), &git.PushOptions{
+			RemoteName:      opts.RemoteName,
+			Auth:            s.auth,
+			InsecureSkipTLS: skipTLS(opts.Insecure, s.httpClient),
+			RefSpecs:        []config.RefSpec{refSpec},
+		})
+		if err == nil || errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return nil
+		}
+
+		if opts.Force || !s.isNonFastForward(ctx, opts.RemoteName, refName, opts.Insecure, err) {
+			return mapError(err)
+		}
+
+		if attempt >= opts.Retries {
+			return fmt.Errorf("%w: %s", ErrNonFastForward, refName)
+		}
+
+		if err := s.replay(ctx, opts.RemoteName, refName, opts.Insecure); err != nil {
+			return err
+		}
+	}
 }
 
-func Pull(s *Repo, insecure bool) error {
+func Pull(ctx context.Context, s *Repo, insecure bool) error {
 	// Get the working directory for the repository
 	wt, err := s.repo.Worktree()
 	if err != nil {
 		return err
 	}
 
-	err = wt.Pull(&git.PullOptions{
+	err = wt.PullContext(withHTTPClient(ctx, s.httpClient), &git.PullOptions{
 		RemoteName:      "origin",
 		Auth:            s.auth,
-		InsecureSkipTLS: insecure,
+		InsecureSkipTLS: skipTLS(insecure, s.httpClient),
 	})
 
 	if err != nil {
 		if errors.Is(err, git.NoErrAlreadyUpToDate) {
-			err = nil
+			return nil
 		}
+		return mapError(err)
 	}
 
-	return err
+	return nil
 }
 
 func getHeadCommit(s *Repo) (*object.Commit, error) {
@@ -278,7 +693,17 @@ func TagExists(tag string, r *git.Repository) (bool, error) {
 	return exists, nil
 }
 
-func (s *Repo) CreateTag(tag string) (bool, error) {
+// TagOpts describes the annotated tag to create.
+type TagOpts struct {
+	// Message: the tag message (default: the tag name).
+	Message string
+	// Tagger: the tag author (default: krateoctl).
+	Tagger *Identity
+}
+
+// CreateTag creates the annotated tag on HEAD; it returns false if the tag
+// already exists.
+func (s *Repo) CreateTag(tag string, opts *TagOpts) (bool, error) {
 	r := s.repo
 
 	exists, err := TagExists(tag, r)
@@ -294,38 +719,69 @@ func (s *Repo) CreateTag(tag string) (bool, error) {
 		return false, err
 	}
 
-	//Info("git tag -a %s %s -m \"%s\"", tag, h.Hash(), tag)
-	_, err = r.CreateTag(tag, h.Hash(), &git.CreateTagOptions{
-		Message: tag,
+	tagger := opts.Tagger
+	if tagger == nil {
+		tagger = &Identity{Name: commitAuthorName, Email: commitAuthorEmail}
+	}
+
+	message := opts.Message
+	if len(message) == 0 {
+		message = tag
+	}
+
+	//Info("git tag -a %s %s -m \"%s\"", tag, h.Hash(), message)
+	ref, err := r.CreateTag(tag, h.Hash(), &git.CreateTagOptions{
+		Tagger: &object.Signature{
+			Name:  tagger.Name,
+			Email: tagger.Email,
+			When:  time.Now(),
+		},
+		Message: message,
 	})
 	if err != nil {
 		return false, err
 	}
 
+	if s.signer != nil {
+		obj, err := r.TagObject(ref.Hash())
+		if err != nil {
+			return false, err
+		}
+
+		hash, err := signTag(r.Storer, s.signer, obj)
+		if err != nil {
+			return false, err
+		}
+
+		err = r.Storer.SetReference(plumbing.NewHashReference(ref.Name(), hash))
+		if err != nil {
+			return false, err
+		}
+	}
+
 	return true, nil
 }
 
-func (s *Repo) PushTags(token string, insecure bool) error {
+// PushTags pushes the tags to origin with the repository credentials.
+func (s *Repo) PushTags(ctx context.Context, insecure bool) error {
 	r := s.repo
 
 	opts := &git.PushOptions{
 		RemoteName: "origin",
 		//Progress:   os.Stdout,
-		RefSpecs: []config.RefSpec{config.RefSpec("refs/tags/*:refs/tags/*")},
-		Auth: &http.TokenAuth{
-			Token: token,
-		},
-		InsecureSkipTLS: insecure,
+		RefSpecs:        []config.RefSpec{config.RefSpec("refs/tags/*:refs/tags/*")},
+		Auth:            s.auth,
+		InsecureSkipTLS: skipTLS(insecure, s.httpClient),
 	}
 	//Info("git push --tags")
-	err := r.Push(opts)
+	err := r.PushContext(withHTTPClient(ctx, s.httpClient), opts)
 	if err != nil {
 		if err == git.NoErrAlreadyUpToDate {
 			//log.Print("origin remote was up to date, no push done")
 			return nil
 		}
 		//log.Printf("push to remote origin error: %s", err)
-		return err
+		return mapError(err)
 	}
 
 	return nil
@@ -0,0 +1,334 @@
+package git
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http/cgi"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+func TestMain(m *testing.M) {
+	// the test repositories are created in temporary directories
+	if err := AllowLocalRepos(os.TempDir()); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestMapError(t *testing.T) {
+	table := []struct {
+		err  error
+		want error
+	}{
+		{transport.ErrRepositoryNotFound, ErrRepositoryNotFound},
+		{transport.ErrEmptyRemoteRepository, ErrEmptyRemoteRepository},
+		{transport.ErrAuthenticationRequired, ErrAuthenticationRequired},
+		{transport.ErrAuthorizationFailed, ErrAuthorizationFailed},
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: x509.UnknownAuthorityError{}}, ErrTLS},
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: os.ErrDeadlineExceeded}, ErrTimeout},
+		{errors.New("command error on refs/heads/main: protected branch hook declined"), ErrProtectedBranch},
+		{errors.New("unpack error: pack exceeds maximum allowed size"), ErrTooLarge},
+		{errors.New("command error on refs/heads/main: You are not allowed to push code to protected branches on this project."), ErrProtectedBranch},
+		{errors.New("remote: error: GH001: Large files detected. You may want to try Git Large File Storage"), ErrTooLarge},
+		{fmt.Errorf("ssh: handshake failed: ssh: unable to authenticate"), ErrAuthenticationRequired},
+	}
+
+	for _, tc := range table {
+		if got := mapError(tc.err); !errors.Is(got, tc.want) {
+			t.Errorf("mapError(%q) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+
+	// unrelated server rejections keep the generic error
+	for _, el := range []string{
+		"command error on refs/heads/main: pre-receive hook declined",
+		"remote: storage quota exceeds the plan limit",
+		"remote: commit message too large for the ticket reference",
+	} {
+		err := errors.New(el)
+		if got := mapError(err); got != err {
+			t.Errorf("mapError(%q) = %v, want it unchanged", el, got)
+		}
+	}
+}
+
+func TestLocalRepo(t *testing.T) {
+	ctx := context.Background()
+
+	dir := filepath.Join(t.TempDir(), "demo.git")
+	if _, err := git.PlainInit(dir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Clone(ctx, &CloneOpts{URL: dir, Shallow: true})
+	if !errors.Is(err, ErrEmptyRemoteRepository) {
+		t.Fatalf("got %v, want %v", err, ErrEmptyRemoteRepository)
+	}
+
+	repo, err := Init(&CloneOpts{URL: dir}, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := repo.FS().Create("README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("hello"))
+	f.Close()
+
+	if _, err := repo.Commit(".", &CommitOpts{Message: "first"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Push(ctx, &PushOpts{RemoteName: "origin", Branch: "main"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, el := range []string{dir, "file://" + dir} {
+		res, err := Clone(ctx, &CloneOpts{URL: el, Ref: "main", Shallow: true})
+		if err != nil {
+			t.Fatalf("%s: %v", el, err)
+		}
+
+		f, err := res.FS().Open("README.md")
+		if err != nil {
+			t.Fatalf("%s: %v", el, err)
+		}
+		got, _ := ioutil.ReadAll(f)
+		f.Close()
+
+		if string(got) != "hello" {
+			t.Errorf("%s: got %q, want %q", el, got, "hello")
+		}
+	}
+}
+
+// newTestRemote returns a bare repository with a commit on the 'main' and
+// 'develop' branches, tagged by 'v1' (lightweight) and 'v2' (annotated).
+func newTestRemote(t *testing.T) (string, plumbing.Hash) {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "remote.git")
+	remote, err := git.PlainInit(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head := plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/main")
+	if err := remote.Storer.SetReference(head); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := Init(&CloneOpts{URL: dir}, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := repo.FS().Create("README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("hello"))
+	f.Close()
+
+	commitId, err := repo.Commit(".", &CommitOpts{Message: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateTag("v2", &TagOpts{Message: "release"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := repo.Push(ctx, &PushOpts{RemoteName: "origin", Branch: "main"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.PushTags(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := plumbing.NewHash(commitId)
+	for _, el := range []plumbing.ReferenceName{"refs/heads/develop", "refs/tags/v1"} {
+		if err := remote.Storer.SetReference(plumbing.NewHashReference(el, hash)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir, hash
+}
+
+// newHTTPRemote serves the repositories in root with 'git http-backend',
+// since the in process file transport does not support shallow fetches.
+func newHTTPRemote(t *testing.T, root string) *httptest.Server {
+	t.Helper()
+
+	bin, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git binary not found")
+	}
+
+	srv := httptest.NewServer(&cgi.Handler{
+		Path: bin,
+		Args: []string{"http-backend"},
+		Env:  []string{"GIT_PROJECT_ROOT=" + root, "GIT_HTTP_EXPORT_ALL=1"},
+	})
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestShallowClone(t *testing.T) {
+	ctx := context.Background()
+
+	dir, _ := newTestRemote(t)
+
+	repo, err := Clone(ctx, &CloneOpts{URL: dir, Ref: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, repo, "CHANGELOG.md", "v3")
+	tip := commitFile(t, repo, "CHANGELOG.md", "v4")
+	if err := repo.Push(ctx, &PushOpts{RemoteName: "origin", Branch: "main"}); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newHTTPRemote(t, filepath.Dir(dir))
+
+	res, err := Clone(ctx, &CloneOpts{URL: srv.URL + "/" + filepath.Base(dir), Ref: "main", Shallow: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commits, err := res.repo.CommitObjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := commits.ForEach(func(*object.Commit) error {
+		count++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("got %d commits, want 1", count)
+	}
+
+	refs, err := res.repo.References()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		switch name := ref.Name(); {
+		case name.IsTag():
+			t.Errorf("unexpected tag %s", name)
+		case name.IsBranch() || name.IsRemote():
+			if name.Short() != "main" && name.Short() != "origin/main" {
+				t.Errorf("unexpected branch %s", name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := res.repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Hash().String() != tip {
+		t.Errorf("got HEAD %s, want %s", head.Hash(), tip)
+	}
+}
+
+func TestResolveRef(t *testing.T) {
+	dir, hash := newTestRemote(t)
+
+	table := []struct {
+		ref      string
+		wantName plumbing.ReferenceName
+		wantHash plumbing.Hash
+	}{
+		// the file server does not advertise the HEAD symref
+		{"", "refs/heads/main", plumbing.ZeroHash},
+		{"develop", "refs/heads/develop", plumbing.ZeroHash},
+		{"refs/heads/develop", "refs/heads/develop", plumbing.ZeroHash},
+		{"v1", "refs/tags/v1", plumbing.ZeroHash},
+		{"v2", "refs/tags/v2", plumbing.ZeroHash},
+		{hash.String(), plumbing.HEAD, hash},
+	}
+
+	for _, tc := range table {
+		name, h, err := resolveRef(context.Background(), &CloneOpts{URL: dir, Ref: tc.ref})
+		if err != nil {
+			t.Fatalf("%q: %v", tc.ref, err)
+		}
+		if name != tc.wantName || h != tc.wantHash {
+			t.Errorf("%q: got (%s, %s), want (%s, %s)", tc.ref, name, h, tc.wantName, tc.wantHash)
+		}
+	}
+
+	_, _, err := resolveRef(context.Background(), &CloneOpts{URL: dir, Ref: "missing"})
+	if !errors.Is(err, ErrReferenceNotFound) {
+		t.Fatalf("got %v, want %v", err, ErrReferenceNotFound)
+	}
+
+	// the remote HEAD of the other servers is cloned without listing the refs
+	name, h, err := resolveRef(context.Background(), &CloneOpts{URL: "https://127.0.0.1:1/demo.git"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != plumbing.HEAD || !h.IsZero() {
+		t.Fatalf("got (%s, %s), want (%s, %s)", name, h, plumbing.HEAD, plumbing.ZeroHash)
+	}
+}
+
+func TestCloneRefs(t *testing.T) {
+	ctx := context.Background()
+
+	dir, hash := newTestRemote(t)
+
+	cache, err := NewCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []*Cache{nil, cache} {
+		// the annotated tag 'v2' is peeled to the tagged commit
+		for _, ref := range []string{"", "develop", "v1", "v2", hash.String()} {
+			repo, err := Clone(ctx, &CloneOpts{URL: dir, Ref: ref, Shallow: true, Cache: c})
+			if err != nil {
+				t.Fatalf("%q (cache: %t): %v", ref, c != nil, err)
+			}
+
+			commit, err := getHeadCommit(repo)
+			if err != nil {
+				t.Fatalf("%q (cache: %t): %v", ref, c != nil, err)
+			}
+			if commit.Hash != hash {
+				t.Errorf("%q (cache: %t): got commit %s, want %s", ref, c != nil, commit.Hash, hash)
+			}
+
+			if ok, err := repo.Exists("README.md"); err != nil || !ok {
+				t.Errorf("%q (cache: %t): missing README.md", ref, c != nil)
+			}
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package git
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	sshSigNamespace = "git"
+	sshSigHashAlgo  = "sha512"
+	sshSigVersion   = 1
+	sshSigPreamble  = "SSHSIG"
+)
+
+// Signer signs the commits and the annotated tags.
+type Signer interface {
+	// Sign returns the armored detached signature of the message.
+	Sign(message io.Reader) ([]byte, error)
+}
+
+// NewOpenPGPSigner returns a signer using the armored OpenPGP private key.
+func NewOpenPGPSigner(armoredKey, passphrase []byte) (Signer, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(armoredKey))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read openpgp key: %w", err)
+	}
+	if len(entities) == 0 {
+		return nil, fmt.Errorf("no openpgp key found")
+	}
+
+	entity := entities[0]
+	if entity.PrivateKey == nil {
+		return nil, fmt.Errorf("openpgp key has no private key")
+	}
+
+	if entity.PrivateKey.Encrypted {
+		if len(passphrase) == 0 {
+			return nil, fmt.Errorf("openpgp key is encrypted but no passphrase was given")
+		}
+
+		if err := entity.PrivateKey.Decrypt(passphrase); err != nil {
+			return nil, fmt.Errorf("cannot decrypt openpgp key: %w", err)
+		}
+		for _, sub := range entity.Subkeys {
+			if sub.PrivateKey != nil && sub.PrivateKey.Encrypted {
+				if err := sub.PrivateKey.Decrypt(passphrase); err != nil {
+					return nil, fmt.Errorf("cannot decrypt openpgp subkey: %w", err)
+				}
+			}
+		}
+	}
+
+	return &openPGPSigner{entity: entity}, nil
+}
+
+type openPGPSigner struct {
+	entity *openpgp.Entity
+}
+
+func (s *openPGPSigner) Sign(message io.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&buf, s.entity, message, nil); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// NewSSHSigner returns a signer producing SSH signatures (as 'git -c gpg.format=ssh')
+// using the PEM or OpenSSH encoded private key.
+func NewSSHSigner(privateKey, passphrase []byte) (Signer, error) {
+	var key ssh.Signer
+	var err error
+	if len(passphrase) > 0 {
+		key, err = ssh.ParsePrivateKeyWithPassphrase(privateKey, passphrase)
+	} else {
+		key, err = ssh.ParsePrivateKey(privateKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse ssh signing key: %w", err)
+	}
+
+	return &sshSigner{key: key}, nil
+}
+
+type sshSigner struct {
+	key ssh.Signer
+}
+
+// Sign implements the SSHSIG protocol described in
+// https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.sshsig
+func (s *sshSigner) Sign(message io.Reader) ([]byte, error) {
+	h := sha512.New()
+	if _, err := io.Copy(h, message); err != nil {
+		return nil, err
+	}
+
+	signed := sshSigBlob(sshSigNamespace, sshSigHashAlgo, h.Sum(nil))
+
+	var sig *ssh.Signature
+	var err error
+	if as, ok := s.key.(ssh.AlgorithmSigner); ok && s.key.PublicKey().Type() == ssh.KeyAlgoRSA {
+		// the SHA-1 based ssh-rsa signatures are rejected by git
+		sig, err = as.SignWithAlgorithm(rand.Reader, signed, ssh.SigAlgoRSASHA2512)
+	} else {
+		sig, err = s.key.Sign(rand.Reader, signed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var blob bytes.Buffer
+	blob.WriteString(sshSigPreamble)
+	binary.Write(&blob, binary.BigEndian, uint32(sshSigVersion))
+	writeSSHString(&blob, s.key.PublicKey().Marshal())
+	writeSSHString(&blob, []byte(sshSigNamespace))
+	writeSSHString(&blob, nil)
+	writeSSHString(&blob, []byte(sshSigHashAlgo))
+	writeSSHString(&blob, ssh.Marshal(sig))
+
+	return armorSSHSignature(blob.Bytes()), nil
+}
+
+// sshSigBlob returns the data actually signed by the ssh key.
+func sshSigBlob(namespace, hashAlgo string, digest []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(sshSigPreamble)
+	writeSSHString(&buf, []byte(namespace))
+	writeSSHString(&buf, nil)
+	writeSSHString(&buf, []byte(hashAlgo))
+	writeSSHString(&buf, digest)
+
+	return buf.Bytes()
+}
+
+func writeSSHString(w *bytes.Buffer, data []byte) {
+	binary.Write(w, binary.BigEndian, uint32(len(data)))
+	w.Write(data)
+}
+
+func armorSSHSignature(blob []byte) []byte {
+	enc := base64.StdEncoding.EncodeToString(blob)
+
+	var sb strings.Builder
+	sb.WriteString("-----BEGIN SSH SIGNATURE-----\n")
+	for len(enc) > 70 {
+		sb.WriteString(enc[:70])
+		sb.WriteByte('\n')
+		enc = enc[70:]
+	}
+	sb.WriteString(enc)
+	sb.WriteString("\n-----END SSH SIGNATURE-----\n")
+
+	return []byte(sb.String())
+}
+
+// signCommit stores a signed copy of the commit and returns its hash.
+func signCommit(st storer.EncodedObjectStorer, signer Signer, commit *object.Commit) (plumbing.Hash, error) {
+	unsigned := st.NewEncodedObject()
+	if err := commit.EncodeWithoutSignature(unsigned); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	r, err := unsigned.Reader()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	defer r.Close()
+
+	sig, err := signer.Sign(r)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("cannot sign commit: %w", err)
+	}
+	commit.PGPSignature = string(sig)
+
+	signed := st.NewEncodedObject()
+	if err := commit.Encode(signed); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return st.SetEncodedObject(signed)
+}
+
+// signTag stores a signed copy of the annotated tag and returns its hash.
+func signTag(st storer.EncodedObjectStorer, signer Signer, tag *object.Tag) (plumbing.Hash, error) {
+	if !strings.HasSuffix(tag.Message, "\n") {
+		// the signature is appended to the message
+		tag.Message += "\n"
+	}
+
+	unsigned := st.NewEncodedObject()
+	if err := tag.EncodeWithoutSignature(unsigned); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	r, err := unsigned.Reader()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	defer r.Close()
+
+	sig, err := signer.Sign(r)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("cannot sign tag: %w", err)
+	}
+	tag.PGPSignature = string(sig)
+
+	signed := st.NewEncodedObject()
+	if err := tag.Encode(signed); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return st.SetEncodedObject(signed)
+}
@@ -0,0 +1,232 @@
+package git
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/go-git/go-git/v5/plumbing"
+	"golang.org/x/crypto/ssh"
+)
+
+// signedObjects returns the payload and the signature of a commit and an
+// annotated tag signed by the signer.
+func signedObjects(t *testing.T, signer Signer) map[string][2]string {
+	t.Helper()
+
+	repo, err := Init(&CloneOpts{URL: "file:///demo.git", Signer: signer}, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := repo.FS().Create("README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("hello"))
+	f.Close()
+
+	commitId, err := repo.Commit(".", &CommitOpts{Message: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateTag("v1", &TagOpts{Message: "release"}); err != nil {
+		t.Fatal(err)
+	}
+
+	commit, err := repo.repo.CommitObject(plumbing.NewHash(commitId))
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitPayload := repo.storer.NewEncodedObject()
+	if err := commit.EncodeWithoutSignature(commitPayload); err != nil {
+		t.Fatal(err)
+	}
+
+	ref, err := repo.repo.Tag("v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, err := repo.repo.TagObject(ref.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag.Target != commit.Hash {
+		t.Fatalf("got tag target %s, want %s", tag.Target, commit.Hash)
+	}
+	// go-git decodes only the PGP signatures of the tags: as git does,
+	// the message is split at the signature
+	if i := strings.Index(tag.Message, "-----BEGIN SSH SIGNATURE-----"); i >= 0 && len(tag.PGPSignature) == 0 {
+		tag.Message, tag.PGPSignature = tag.Message[:i], tag.Message[i:]
+	}
+	tagPayload := repo.storer.NewEncodedObject()
+	if err := tag.EncodeWithoutSignature(tagPayload); err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string][2]string{
+		"commit": {readObject(t, commitPayload), commit.PGPSignature},
+		"tag":    {readObject(t, tagPayload), tag.PGPSignature},
+	}
+}
+
+func readObject(t *testing.T, obj plumbing.EncodedObject) string {
+	t.Helper()
+
+	r, err := obj.Reader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	res, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(res)
+}
+
+// sshSignedData returns the data signed by the ssh key (PROTOCOL.sshsig).
+func sshSignedData(payload string) []byte {
+	digest := sha512.Sum512([]byte(payload))
+
+	return append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{"git", "", "sha512", digest[:]})...)
+}
+
+// verifySSHSignature verifies the armored signature as 'ssh-keygen -Y verify'.
+func verifySSHSignature(t *testing.T, key ssh.PublicKey, payload, armored string) {
+	t.Helper()
+
+	const begin, end = "-----BEGIN SSH SIGNATURE-----", "-----END SSH SIGNATURE-----"
+	armored = strings.TrimSpace(armored)
+	if !strings.HasPrefix(armored, begin) || !strings.HasSuffix(armored, end) {
+		t.Fatalf("not an armored ssh signature: %q", armored)
+	}
+	enc := strings.ReplaceAll(strings.TrimSuffix(strings.TrimPrefix(armored, begin), end), "\n", "")
+
+	blob, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(blob, []byte("SSHSIG")) {
+		t.Fatalf("missing the SSHSIG preamble")
+	}
+
+	var wrapper struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     []byte
+	}
+	if err := ssh.Unmarshal(blob[len("SSHSIG"):], &wrapper); err != nil {
+		t.Fatal(err)
+	}
+	if wrapper.Version != 1 || wrapper.Namespace != "git" || wrapper.HashAlgorithm != "sha512" {
+		t.Fatalf("unexpected signature header: %d %s %s", wrapper.Version, wrapper.Namespace, wrapper.HashAlgorithm)
+	}
+	if !bytes.Equal(wrapper.PublicKey, key.Marshal()) {
+		t.Fatalf("signed by another key")
+	}
+
+	sig := &ssh.Signature{}
+	if err := ssh.Unmarshal(wrapper.Signature, sig); err != nil {
+		t.Fatal(err)
+	}
+	if sig.Format == ssh.KeyAlgoRSA {
+		t.Fatalf("got a SHA-1 rsa signature")
+	}
+
+	if err := key.Verify(sshSignedData(payload), sig); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := key.Verify(sshSignedData(payload+"tampered"), sig); err == nil {
+		t.Fatalf("expected a tampered payload not to verify")
+	}
+}
+
+func TestSSHSigner(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	for name, key := range map[string][]byte{"rsa": rsaPEM, "ed25519": edPEM} {
+		signer, err := NewSSHSigner(key, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		pub := signer.(*sshSigner).key.PublicKey()
+		for obj, el := range signedObjects(t, signer) {
+			t.Run(name+" "+obj, func(t *testing.T) {
+				verifySSHSignature(t, pub, el[0], el[1])
+			})
+		}
+	}
+}
+
+func TestOpenPGPSigner(t *testing.T) {
+	entity, err := openpgp.NewEntity("Krateo", "", "krateo@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var key bytes.Buffer
+	w, err := armor.Encode(&key, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SerializePrivate(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	signer, err := NewOpenPGPSigner(key.Bytes(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyring := openpgp.EntityList{entity}
+	for obj, el := range signedObjects(t, signer) {
+		_, err := openpgp.CheckArmoredDetachedSignature(keyring, strings.NewReader(el[0]), strings.NewReader(el[1]), nil)
+		if err != nil {
+			t.Fatalf("%s: %v", obj, err)
+		}
+
+		_, err = openpgp.CheckArmoredDetachedSignature(keyring, strings.NewReader(el[0]+"tampered"), strings.NewReader(el[1]), nil)
+		if err == nil {
+			t.Fatalf("%s: expected a tampered payload not to verify", obj)
+		}
+	}
+}
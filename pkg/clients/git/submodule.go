@@ -0,0 +1,44 @@
+package git
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// updateSubmodules checks out the submodules of the repository worktree,
+// recursively. go-git would update the nested ones without the request
+// context, thus without the configured http client.
+func updateSubmodules(ctx context.Context, repo *git.Repository, opts *CloneOpts) error {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	subs, err := wt.Submodules()
+	if err != nil {
+		return err
+	}
+
+	for _, sm := range subs {
+		err := sm.UpdateContext(withHTTPClient(ctx, opts.HTTPClient), &git.SubmoduleUpdateOptions{
+			Init: true,
+			Auth: endpointAuth(sm.Config().URL, opts.Auth),
+		})
+		if err != nil {
+			return fmt.Errorf("submodule %s: %w", sm.Config().Path, mapError(err))
+		}
+
+		sub, err := sm.Repository()
+		if err != nil {
+			return err
+		}
+
+		if err := updateSubmodules(ctx, sub, opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
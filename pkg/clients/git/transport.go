@@ -0,0 +1,220 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+type httpClientKey struct{}
+
+// The http(s) transports are installed once for the whole process, so the
+// client configured for each operation (TLS, proxy) travels in the request
+// context and the shared transport dispatches the request to it.
+func init() {
+	cl := githttp.NewClient(&http.Client{
+		Transport: contextTransport{},
+	})
+
+	client.InstallProtocol("http", cl)
+	client.InstallProtocol("https", cl)
+
+	// the default file transport runs the git binary, missing in the image
+	client.InstallProtocol("file", server.NewServer(fileLoader{}))
+}
+
+// localRepos are the directories the local repositories are served from.
+var localRepos struct {
+	sync.RWMutex
+	root string
+	// denied: the directories under root never served (i.e. the cache)
+	denied []string
+}
+
+// AllowLocalRepos serves the local repositories (file:// URLs and paths)
+// under the root directory; with an empty root, the default, none is served.
+func AllowLocalRepos(root string) error {
+	if len(root) > 0 {
+		var err error
+		if root, err = realPath(root); err != nil {
+			return err
+		}
+	}
+
+	localRepos.Lock()
+	defer localRepos.Unlock()
+
+	localRepos.root = root
+
+	return nil
+}
+
+// denyLocalRepos never serves the local repositories under dir.
+func denyLocalRepos(dir string) error {
+	dir, err := realPath(dir)
+	if err != nil {
+		return err
+	}
+
+	localRepos.Lock()
+	defer localRepos.Unlock()
+
+	localRepos.denied = append(localRepos.denied, dir)
+
+	return nil
+}
+
+// checkLocalRepo tells whether the local repository can be served.
+func checkLocalRepo(dir string) error {
+	localRepos.RLock()
+	defer localRepos.RUnlock()
+
+	if len(localRepos.root) == 0 {
+		return fmt.Errorf("%w: %s (local repositories are disabled)", ErrLocalRepoNotAllowed, dir)
+	}
+
+	real, err := realPath(dir)
+	if err != nil {
+		return err
+	}
+
+	if !isWithin(localRepos.root, real) {
+		return fmt.Errorf("%w: %s is outside %s", ErrLocalRepoNotAllowed, dir, localRepos.root)
+	}
+
+	for _, el := range localRepos.denied {
+		if isWithin(el, real) {
+			return fmt.Errorf("%w: %s is in %s", ErrLocalRepoNotAllowed, dir, el)
+		}
+	}
+
+	return nil
+}
+
+// realPath returns the absolute path with the symlinks, if any, resolved;
+// the missing trailing elements are kept as they are.
+func realPath(p string) (string, error) {
+	p, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := filepath.EvalSymlinks(p)
+	if err == nil {
+		return res, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	dir, name := filepath.Split(p)
+	if dir == p || len(name) == 0 {
+		return p, nil
+	}
+
+	res, err = realPath(filepath.Clean(dir))
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(res, name), nil
+}
+
+func isWithin(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// fileLoader loads the local repositories allowed: the bare ones or, for
+// the others, their '.git' directory.
+type fileLoader struct{}
+
+func (fileLoader) Load(ep *transport.Endpoint) (storer.Storer, error) {
+	if err := checkLocalRepo(ep.Path); err != nil {
+		return nil, err
+	}
+
+	st, err := server.DefaultLoader.Load(ep)
+	if !errors.Is(err, transport.ErrRepositoryNotFound) {
+		return st, err
+	}
+
+	dotGit := *ep
+	dotGit.Path = path.Join(ep.Path, ".git")
+
+	return server.DefaultLoader.Load(&dotGit)
+}
+
+type contextTransport struct{}
+
+func (contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cl, _ := req.Context().Value(httpClientKey{}).(*http.Client)
+	if cl == nil || cl.Transport == nil {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+
+	return cl.Transport.RoundTrip(req)
+}
+
+// withHTTPClient returns a context routing the http(s) requests through cl.
+func withHTTPClient(ctx context.Context, cl *http.Client) context.Context {
+	if cl == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, httpClientKey{}, cl)
+}
+
+// skipTLS tells whether go-git must skip the TLS verification: with a custom
+// http client it must not, since go-git would replace the client with its own.
+func skipTLS(insecure bool, cl *http.Client) bool {
+	return insecure && cl == nil
+}
+
+// endpointAuth returns the auth for the URL: the ssh keys authenticate as
+// the URL user, if any, instead of the default one.
+func endpointAuth(rawURL string, auth transport.AuthMethod) transport.AuthMethod {
+	keys, ok := auth.(*gitssh.PublicKeys)
+	if !ok {
+		return auth
+	}
+
+	ep, err := transport.NewEndpoint(rawURL)
+	if err != nil || len(ep.User) == 0 || ep.User == keys.User {
+		return auth
+	}
+
+	res := *keys
+	res.User = ep.User
+
+	return &res
+}
+
+// shallowDepth returns the depth of the shallow fetches from the remote;
+// the file transport cannot serve them, so it fetches the full history.
+func shallowDepth(rawURL string) int {
+	if isLocal(rawURL) {
+		return 0
+	}
+
+	return 1
+}
+
+// isLocal tells whether the URL is served by the file transport.
+func isLocal(rawURL string) bool {
+	ep, err := transport.NewEndpoint(rawURL)
+	return err == nil && ep.Protocol == "file"
+}
@@ -0,0 +1,111 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+func TestEndpointAuth(t *testing.T) {
+	keys := &gitssh.PublicKeys{User: "git"}
+
+	table := []struct {
+		url  string
+		user string
+	}{
+		{"ssh://deploy@git.example.com/org/repo.git", "deploy"},
+		{"ssh://git.example.com:2222/org/repo.git", "git"},
+		{"alice@git.example.com:org/repo.git", "alice"},
+		{"git@github.com:org/repo.git", "git"},
+	}
+
+	for _, tc := range table {
+		res, ok := endpointAuth(tc.url, keys).(*gitssh.PublicKeys)
+		if !ok {
+			t.Fatalf("%s: unexpected auth type", tc.url)
+		}
+		if res.User != tc.user {
+			t.Errorf("%s: got user %q, want %q", tc.url, res.User, tc.user)
+		}
+	}
+
+	if keys.User != "git" {
+		t.Fatalf("the configured auth must not be modified")
+	}
+
+	basic := &githttp.BasicAuth{Username: "abc123", Password: "token"}
+	if endpointAuth("https://bob@example.com/org/repo.git", basic) != basic {
+		t.Fatalf("non ssh auth must be returned as is")
+	}
+}
+
+func TestLocalReposRoot(t *testing.T) {
+	ctx := context.Background()
+
+	root := t.TempDir()
+	t.Cleanup(func() { AllowLocalRepos(os.TempDir()) })
+
+	inside := filepath.Join(root, "inside.git")
+	if _, err := git.PlainInit(inside, true); err != nil {
+		t.Fatal(err)
+	}
+
+	outside, _ := newTestRemote(t)
+
+	// a symlink under the root to a repository outside
+	link := filepath.Join(root, "link.git")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := NewCache(filepath.Join(root, "cache"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Clone(ctx, &CloneOpts{URL: outside, Cache: cache}); err != nil {
+		t.Fatal(err)
+	}
+	key, err := cacheKey(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rel, err := filepath.Rel(root, outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AllowLocalRepos(root); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Clone(ctx, &CloneOpts{URL: "file://" + inside}); !errors.Is(err, ErrEmptyRemoteRepository) {
+		t.Fatalf("%s: got %v, want %v", inside, err, ErrEmptyRemoteRepository)
+	}
+
+	for _, el := range []string{
+		outside,
+		"file://" + outside,
+		root + string(filepath.Separator) + rel,
+		link,
+		filepath.Join(root, "cache", key),
+	} {
+		if _, err := Clone(ctx, &CloneOpts{URL: el}); !errors.Is(err, ErrLocalRepoNotAllowed) {
+			t.Errorf("%s: got %v, want %v", el, err, ErrLocalRepoNotAllowed)
+		}
+	}
+
+	if err := AllowLocalRepos(""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Clone(ctx, &CloneOpts{URL: inside}); !errors.Is(err, ErrLocalRepoNotAllowed) {
+		t.Fatalf("%s: got %v, want %v", inside, err, ErrLocalRepoNotAllowed)
+	}
+}
@@ -0,0 +1,48 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	gohttp "net/http"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/krateoplatformops/provider-git/apis/v1alpha1"
+	"github.com/krateoplatformops/provider-git/pkg/clients/hosting"
+	"github.com/krateoplatformops/provider-git/pkg/helpers"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// getHostingProvider returns the target repo hosting service client, if configured.
+func getHostingProvider(ctx context.Context, k client.Client, pc *v1alpha1.ProviderConfig,
+	toRepoCreds transport.AuthMethod, cl *gohttp.Client) (hosting.Provider, error) {
+	api := pc.Spec.ToRepoApi
+	if api == nil {
+		return nil, nil
+	}
+
+	var token string
+	if api.TokenSecretRef != nil {
+		var err error
+		token, err = helpers.GetSecret(ctx, k, api.TokenSecretRef.DeepCopy())
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		switch creds := toRepoCreds.(type) {
+		case *http.TokenAuth:
+			token = creds.Token
+		case *http.BasicAuth:
+			token = creds.Password
+		default:
+			return nil, fmt.Errorf("no api token: 'tokenSecretRef' must be specified")
+		}
+	}
+
+	return hosting.New(&hosting.Opts{
+		Kind:       api.Kind,
+		Url:        api.Url,
+		Token:      token,
+		HTTPClient: cl,
+	})
+}
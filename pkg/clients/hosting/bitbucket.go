@@ -0,0 +1,129 @@
+package hosting
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/carlmjohnson/requests"
+)
+
+// bitbucket is Bitbucket Server (and Data Center).
+type bitbucket struct {
+	*client
+}
+
+func (p *bitbucket) request(path string) *requests.Builder {
+	return p.client.request(path).
+		Bearer(p.token)
+}
+
+func (p *bitbucket) CreateRepo(ctx context.Context, opts *CreateRepoOpts) error {
+	// the http clone urls path is '/scm/{project}/{repo}.git'
+	project := strings.TrimPrefix(opts.Owner, "scm/")
+	if len(project) == 0 {
+		return fmt.Errorf("the project owning the repository must be specified")
+	}
+
+	ok, err := exists(ctx, p.request(fmt.Sprintf("projects/%s/repos/%s", url.PathEscape(project), url.PathEscape(opts.Name))))
+	if err != nil || ok {
+		return err
+	}
+
+	body := map[string]interface{}{
+		"name":   opts.Name,
+		"scmId":  "git",
+		"public": opts.Visibility == VisibilityPublic,
+	}
+	if len(opts.Description) > 0 {
+		body["description"] = opts.Description
+	}
+
+	return p.request(fmt.Sprintf("projects/%s/repos", url.PathEscape(project))).
+		BodyJSON(body).
+		CheckStatus(http.StatusCreated).
+		Fetch(ctx)
+}
+
+type bitbucketPull struct {
+	State string `json:"state"`
+	ToRef struct {
+		ID string `json:"id"`
+	} `json:"toRef"`
+	Links struct {
+		Self []struct {
+			Href string `json:"href"`
+		} `json:"self"`
+	} `json:"links"`
+}
+
+func (pr *bitbucketPull) pullRequest() *PullRequest {
+	state := PullRequestOpen
+	switch pr.State {
+	case "MERGED":
+		state = PullRequestMerged
+	case "DECLINED":
+		state = PullRequestClosed
+	}
+
+	ret := &PullRequest{State: state}
+	if len(pr.Links.Self) > 0 {
+		ret.Url = pr.Links.Self[0].Href
+	}
+
+	return ret
+}
+
+func (p *bitbucket) OpenPullRequest(ctx context.Context, opts *PullRequestOpts) (*PullRequest, error) {
+	return openPullRequest(ctx, p, opts)
+}
+
+func (p *bitbucket) FindPullRequest(ctx context.Context, opts *PullRequestOpts) (*PullRequest, error) {
+	var res struct {
+		Values []bitbucketPull `json:"values"`
+	}
+	err := p.request(p.pullRequestsPath(opts)).
+		Param("state", "ALL").
+		Param("direction", "OUTGOING").
+		Param("at", "refs/heads/"+opts.Head).
+		Param("order", "NEWEST").
+		ToJSON(&res).
+		Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, el := range res.Values {
+		if el.ToRef.ID == "refs/heads/"+opts.Base {
+			return el.pullRequest(), nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (p *bitbucket) createPullRequest(ctx context.Context, opts *PullRequestOpts) (*PullRequest, error) {
+	var res bitbucketPull
+	err := p.request(p.pullRequestsPath(opts)).
+		BodyJSON(map[string]interface{}{
+			"title":       opts.Title,
+			"description": opts.Description,
+			"fromRef":     map[string]string{"id": "refs/heads/" + opts.Head},
+			"toRef":       map[string]string{"id": "refs/heads/" + opts.Base},
+		}).
+		CheckStatus(http.StatusCreated).
+		ToJSON(&res).
+		Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.pullRequest(), nil
+}
+
+func (p *bitbucket) pullRequestsPath(opts *PullRequestOpts) string {
+	return fmt.Sprintf("projects/%s/repos/%s/pull-requests",
+		url.PathEscape(strings.TrimPrefix(opts.Owner, "scm/")), url.PathEscape(opts.Name))
+}
@@ -0,0 +1,138 @@
+package hosting
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/carlmjohnson/requests"
+)
+
+type gitea struct {
+	*client
+}
+
+func (p *gitea) request(path string) *requests.Builder {
+	return p.client.request(path).
+		Header("Authorization", "token "+p.token)
+}
+
+func (p *gitea) CreateRepo(ctx context.Context, opts *CreateRepoOpts) error {
+	user, err := p.currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	owner := opts.Owner
+	if len(owner) == 0 {
+		owner = user
+	}
+
+	ok, err := exists(ctx, p.request(fmt.Sprintf("repos/%s/%s", url.PathEscape(owner), url.PathEscape(opts.Name))))
+	if err != nil || ok {
+		return err
+	}
+
+	// gitea has no internal repositories
+	body := map[string]interface{}{
+		"name":        opts.Name,
+		"description": opts.Description,
+		"private":     opts.Visibility != VisibilityPublic,
+	}
+
+	path := "user/repos"
+	if !strings.EqualFold(owner, user) {
+		path = "orgs/" + url.PathEscape(owner) + "/repos"
+	}
+
+	return p.request(path).
+		BodyJSON(body).
+		CheckStatus(http.StatusCreated).
+		Fetch(ctx)
+}
+
+func (p *gitea) currentUser(ctx context.Context) (string, error) {
+	var res struct {
+		Login string `json:"login"`
+	}
+
+	err := p.request("user").
+		ToJSON(&res).
+		Fetch(ctx)
+
+	return res.Login, err
+}
+
+type giteaPull struct {
+	HtmlUrl string `json:"html_url"`
+	State   string `json:"state"`
+	Merged  bool   `json:"merged"`
+	Head    struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+func (pr *giteaPull) pullRequest() *PullRequest {
+	state := PullRequestOpen
+	switch {
+	case pr.Merged:
+		state = PullRequestMerged
+	case pr.State == "closed":
+		state = PullRequestClosed
+	}
+
+	return &PullRequest{Url: pr.HtmlUrl, State: state}
+}
+
+func (p *gitea) OpenPullRequest(ctx context.Context, opts *PullRequestOpts) (*PullRequest, error) {
+	return openPullRequest(ctx, p, opts)
+}
+
+func (p *gitea) FindPullRequest(ctx context.Context, opts *PullRequestOpts) (*PullRequest, error) {
+	// the pulls list cannot be filtered by branch: page through it, up to
+	// the first empty page since the server may cap the page size
+	for page := 1; ; page++ {
+		var res []giteaPull
+		err := p.request(fmt.Sprintf("repos/%s/%s/pulls", url.PathEscape(opts.Owner), url.PathEscape(opts.Name))).
+			Param("state", "all").
+			Param("sort", "recentupdate").
+			Param("limit", "50").
+			Param("page", strconv.Itoa(page)).
+			ToJSON(&res).
+			Fetch(ctx)
+		if err != nil || len(res) == 0 {
+			return nil, err
+		}
+
+		for _, el := range res {
+			if el.Head.Ref == opts.Head && el.Base.Ref == opts.Base {
+				return el.pullRequest(), nil
+			}
+		}
+	}
+}
+
+func (p *gitea) createPullRequest(ctx context.Context, opts *PullRequestOpts) (*PullRequest, error) {
+	var res giteaPull
+	err := p.request(fmt.Sprintf("repos/%s/%s/pulls", url.PathEscape(opts.Owner), url.PathEscape(opts.Name))).
+		BodyJSON(map[string]interface{}{
+			"title": opts.Title,
+			"head":  opts.Head,
+			"base":  opts.Base,
+			"body":  opts.Description,
+		}).
+		CheckStatus(http.StatusCreated).
+		ToJSON(&res).
+		Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.pullRequest(), nil
+}
@@ -0,0 +1,127 @@
+package hosting
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/carlmjohnson/requests"
+)
+
+type gitHub struct {
+	*client
+}
+
+func (p *gitHub) request(path string) *requests.Builder {
+	return p.client.request(path).
+		Bearer(p.token).
+		Accept("application/vnd.github+json")
+}
+
+func (p *gitHub) CreateRepo(ctx context.Context, opts *CreateRepoOpts) error {
+	user, err := p.currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	owner := opts.Owner
+	if len(owner) == 0 {
+		owner = user
+	}
+
+	ok, err := exists(ctx, p.request(fmt.Sprintf("repos/%s/%s", url.PathEscape(owner), url.PathEscape(opts.Name))))
+	if err != nil || ok {
+		return err
+	}
+
+	body := map[string]interface{}{
+		"name":        opts.Name,
+		"description": opts.Description,
+		"private":     opts.Visibility != VisibilityPublic,
+	}
+	if opts.Visibility == VisibilityInternal {
+		body["visibility"] = VisibilityInternal
+	}
+
+	path := "user/repos"
+	if !strings.EqualFold(owner, user) {
+		path = "orgs/" + url.PathEscape(owner) + "/repos"
+	}
+
+	return p.request(path).
+		BodyJSON(body).
+		CheckStatus(http.StatusCreated).
+		Fetch(ctx)
+}
+
+func (p *gitHub) currentUser(ctx context.Context) (string, error) {
+	var res struct {
+		Login string `json:"login"`
+	}
+
+	err := p.request("user").
+		ToJSON(&res).
+		Fetch(ctx)
+
+	return res.Login, err
+}
+
+type gitHubPull struct {
+	HtmlUrl  string  `json:"html_url"`
+	State    string  `json:"state"`
+	MergedAt *string `json:"merged_at"`
+}
+
+func (pr *gitHubPull) pullRequest() *PullRequest {
+	state := PullRequestOpen
+	switch {
+	case pr.MergedAt != nil:
+		state = PullRequestMerged
+	case pr.State == "closed":
+		state = PullRequestClosed
+	}
+
+	return &PullRequest{Url: pr.HtmlUrl, State: state}
+}
+
+func (p *gitHub) OpenPullRequest(ctx context.Context, opts *PullRequestOpts) (*PullRequest, error) {
+	return openPullRequest(ctx, p, opts)
+}
+
+func (p *gitHub) FindPullRequest(ctx context.Context, opts *PullRequestOpts) (*PullRequest, error) {
+	var res []gitHubPull
+	err := p.request(fmt.Sprintf("repos/%s/%s/pulls", url.PathEscape(opts.Owner), url.PathEscape(opts.Name))).
+		Param("state", "all").
+		Param("head", opts.Owner+":"+opts.Head).
+		Param("base", opts.Base).
+		Param("sort", "created").
+		Param("direction", "desc").
+		ToJSON(&res).
+		Fetch(ctx)
+	if err != nil || len(res) == 0 {
+		return nil, err
+	}
+
+	return res[0].pullRequest(), nil
+}
+
+func (p *gitHub) createPullRequest(ctx context.Context, opts *PullRequestOpts) (*PullRequest, error) {
+	var res gitHubPull
+	err := p.request(fmt.Sprintf("repos/%s/%s/pulls", url.PathEscape(opts.Owner), url.PathEscape(opts.Name))).
+		BodyJSON(map[string]interface{}{
+			"title": opts.Title,
+			"head":  opts.Head,
+			"base":  opts.Base,
+			"body":  opts.Description,
+		}).
+		CheckStatus(http.StatusCreated).
+		ToJSON(&res).
+		Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.pullRequest(), nil
+}
@@ -0,0 +1,135 @@
+package hosting
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/carlmjohnson/requests"
+)
+
+type gitLab struct {
+	*client
+}
+
+func (p *gitLab) request(path string) *requests.Builder {
+	return p.client.request(path).
+		Bearer(p.token)
+}
+
+func (p *gitLab) CreateRepo(ctx context.Context, opts *CreateRepoOpts) error {
+	owner := opts.Owner
+	if len(owner) == 0 {
+		user, err := p.currentUser(ctx)
+		if err != nil {
+			return err
+		}
+		owner = user
+	}
+
+	ok, err := exists(ctx, p.request("projects/"+url.PathEscape(owner+"/"+opts.Name)))
+	if err != nil || ok {
+		return err
+	}
+
+	visibility := opts.Visibility
+	if len(visibility) == 0 {
+		visibility = VisibilityPrivate
+	}
+
+	body := map[string]interface{}{
+		"name":        opts.Name,
+		"path":        opts.Name,
+		"description": opts.Description,
+		"visibility":  visibility,
+	}
+
+	if len(opts.Owner) > 0 {
+		var ns struct {
+			ID int `json:"id"`
+		}
+
+		err := p.request("namespaces/" + url.PathEscape(opts.Owner)).
+			ToJSON(&ns).
+			Fetch(ctx)
+		if err != nil {
+			return err
+		}
+
+		body["namespace_id"] = ns.ID
+	}
+
+	return p.request("projects").
+		BodyJSON(body).
+		CheckStatus(http.StatusCreated).
+		Fetch(ctx)
+}
+
+func (p *gitLab) currentUser(ctx context.Context) (string, error) {
+	var res struct {
+		Username string `json:"username"`
+	}
+
+	err := p.request("user").
+		ToJSON(&res).
+		Fetch(ctx)
+
+	return res.Username, err
+}
+
+type gitLabMergeRequest struct {
+	WebUrl string `json:"web_url"`
+	State  string `json:"state"`
+}
+
+func (mr *gitLabMergeRequest) pullRequest() *PullRequest {
+	// 'locked' is transitional, while merging
+	state := PullRequestOpen
+	switch mr.State {
+	case "merged":
+		state = PullRequestMerged
+	case "closed":
+		state = PullRequestClosed
+	}
+
+	return &PullRequest{Url: mr.WebUrl, State: state}
+}
+
+func (p *gitLab) OpenPullRequest(ctx context.Context, opts *PullRequestOpts) (*PullRequest, error) {
+	return openPullRequest(ctx, p, opts)
+}
+
+func (p *gitLab) FindPullRequest(ctx context.Context, opts *PullRequestOpts) (*PullRequest, error) {
+	var res []gitLabMergeRequest
+	err := p.request("projects/"+url.PathEscape(opts.Owner+"/"+opts.Name)+"/merge_requests").
+		Param("source_branch", opts.Head).
+		Param("target_branch", opts.Base).
+		Param("order_by", "created_at").
+		Param("sort", "desc").
+		ToJSON(&res).
+		Fetch(ctx)
+	if err != nil || len(res) == 0 {
+		return nil, err
+	}
+
+	return res[0].pullRequest(), nil
+}
+
+func (p *gitLab) createPullRequest(ctx context.Context, opts *PullRequestOpts) (*PullRequest, error) {
+	var res gitLabMergeRequest
+	err := p.request("projects/" + url.PathEscape(opts.Owner+"/"+opts.Name) + "/merge_requests").
+		BodyJSON(map[string]interface{}{
+			"source_branch": opts.Head,
+			"target_branch": opts.Base,
+			"title":         opts.Title,
+			"description":   opts.Description,
+		}).
+		CheckStatus(http.StatusCreated).
+		ToJSON(&res).
+		Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.pullRequest(), nil
+}
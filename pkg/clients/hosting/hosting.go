@@ -0,0 +1,188 @@
+package hosting
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/carlmjohnson/requests"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// Kinds of git hosting service.
+const (
+	KindGitHub    = "github"
+	KindGitLab    = "gitlab"
+	KindBitbucket = "bitbucket"
+	KindGitea     = "gitea"
+)
+
+// Visibilities of a repository.
+const (
+	VisibilityPrivate  = "private"
+	VisibilityInternal = "internal"
+	VisibilityPublic   = "public"
+)
+
+// States of a pull request.
+const (
+	PullRequestOpen   = "open"
+	PullRequestMerged = "merged"
+	PullRequestClosed = "closed"
+)
+
+// Provider manages repositories through the REST API of a git hosting service.
+type Provider interface {
+	// CreateRepo creates the repository, if it does not exist yet.
+	CreateRepo(ctx context.Context, opts *CreateRepoOpts) error
+
+	// OpenPullRequest opens the pull (merge) request from the head to the
+	// base branch, unless one is already open, and returns it.
+	OpenPullRequest(ctx context.Context, opts *PullRequestOpts) (*PullRequest, error)
+
+	// FindPullRequest returns the most recent pull request from the head to
+	// the base branch, nil if there is none.
+	FindPullRequest(ctx context.Context, opts *PullRequestOpts) (*PullRequest, error)
+}
+
+// CreateRepoOpts describes the repository to create.
+type CreateRepoOpts struct {
+	// Owner: the organization, group, project or user owning the repository
+	// (default: the authenticated user; required by Bitbucket Server).
+	Owner string
+	// Name: the repository name.
+	Name string
+	// Description: the repository description.
+	Description string
+	// Visibility: one of 'private', 'internal' or 'public' (default: private).
+	Visibility string
+}
+
+// PullRequestOpts describes a pull request.
+type PullRequestOpts struct {
+	// Owner: the organization, group, project or user owning the repository.
+	Owner string
+	// Name: the repository name.
+	Name string
+	// Head: the source branch.
+	Head string
+	// Base: the target branch.
+	Base string
+	// Title: the pull request title.
+	Title string
+	// Description: the pull request description.
+	Description string
+}
+
+// PullRequest is the observed pull request.
+type PullRequest struct {
+	// Url: the pull request web page.
+	Url string
+	// State: one of 'open', 'merged' or 'closed'.
+	State string
+}
+
+// Opts describes how to reach the REST API.
+type Opts struct {
+	// Kind: one of 'github', 'gitlab', 'bitbucket' (Bitbucket Server) or 'gitea'.
+	Kind string
+	// Url: the REST API base URL.
+	Url string
+	// Token: the API access token.
+	Token string
+	// HTTPClient: the client used for the API calls (default: http.DefaultClient).
+	HTTPClient *http.Client
+}
+
+// New returns the provider for the kind of hosting service.
+func New(opts *Opts) (Provider, error) {
+	if len(opts.Url) == 0 {
+		return nil, fmt.Errorf("api url must be specified")
+	}
+
+	c := &client{
+		baseUrl: strings.TrimSuffix(opts.Url, "/") + "/",
+		token:   opts.Token,
+		cl:      opts.HTTPClient,
+	}
+	if c.cl == nil {
+		c.cl = http.DefaultClient
+	}
+
+	switch strings.ToLower(opts.Kind) {
+	case KindGitHub:
+		return &gitHub{c}, nil
+	case KindGitLab:
+		return &gitLab{c}, nil
+	case KindBitbucket:
+		return &bitbucket{c}, nil
+	case KindGitea:
+		return &gitea{c}, nil
+	default:
+		return nil, fmt.Errorf("unsupported api kind: %s", opts.Kind)
+	}
+}
+
+// RepoFromURL returns the owner (all the path segments but the last) and
+// the name of the repository.
+func RepoFromURL(repoUrl string) (owner, name string, err error) {
+	ep, err := transport.NewEndpoint(repoUrl)
+	if err != nil {
+		return "", "", err
+	}
+
+	path := strings.Trim(strings.TrimSuffix(strings.Trim(ep.Path, "/"), ".git"), "/")
+
+	idx := strings.LastIndex(path, "/")
+	if idx < 0 {
+		return "", path, nil
+	}
+
+	return path[:idx], path[idx+1:], nil
+}
+
+type client struct {
+	baseUrl string
+	token   string
+	cl      *http.Client
+}
+
+// request returns a builder for the API path, which must be already
+// escaped (i.e. GitLab wants slashes escaped in the project ids).
+func (c *client) request(path string) *requests.Builder {
+	return requests.URL(c.baseUrl + path).Client(c.cl)
+}
+
+// exists tells whether the GET request succeeds or fails with not found.
+func exists(ctx context.Context, rb *requests.Builder) (bool, error) {
+	err := rb.CheckStatus(http.StatusOK).Fetch(ctx)
+	if err == nil {
+		return true, nil
+	}
+
+	if requests.HasStatusErr(err, http.StatusNotFound) {
+		return false, nil
+	}
+
+	return false, err
+}
+
+type pullRequester interface {
+	FindPullRequest(ctx context.Context, opts *PullRequestOpts) (*PullRequest, error)
+	createPullRequest(ctx context.Context, opts *PullRequestOpts) (*PullRequest, error)
+}
+
+// openPullRequest returns the open pull request from the head to the base
+// branch, creating it if missing.
+func openPullRequest(ctx context.Context, p pullRequester, opts *PullRequestOpts) (*PullRequest, error) {
+	pr, err := p.FindPullRequest(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if pr != nil && pr.State == PullRequestOpen {
+		return pr, nil
+	}
+
+	return p.createPullRequest(ctx, opts)
+}
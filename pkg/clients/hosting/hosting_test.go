@@ -0,0 +1,340 @@
+package hosting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type call struct {
+	Method string
+	Path   string
+	Auth   string
+	Body   map[string]interface{}
+}
+
+// fakeServer records the calls and answers with the canned responses
+// keyed by "METHOD path" (404 for the unknown ones), as a single page.
+func fakeServer(t *testing.T, responses map[string]string) (*httptest.Server, *[]call) {
+	var calls []call
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := call{Method: r.Method, Path: r.URL.EscapedPath(), Auth: r.Header.Get("Authorization")}
+		if r.Body != nil {
+			json.NewDecoder(r.Body).Decode(&c.Body)
+		}
+		calls = append(calls, c)
+
+		res, ok := responses[r.Method+" "+c.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if page := r.URL.Query().Get("page"); len(page) > 0 && page != "1" {
+			res = "[]"
+		}
+
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(res))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &calls
+}
+
+func TestCreateRepo(t *testing.T) {
+	table := []struct {
+		kind      string
+		base      string
+		opts      CreateRepoOpts
+		responses map[string]string
+		want      call
+	}{
+		{
+			kind: KindGitHub,
+			base: "/",
+			opts: CreateRepoOpts{Owner: "acme", Name: "demo", Visibility: VisibilityInternal},
+			responses: map[string]string{
+				"GET /user":             `{"login":"bot"}`,
+				"POST /orgs/acme/repos": `{}`,
+			},
+			want: call{Method: "POST", Path: "/orgs/acme/repos", Auth: "Bearer s3cr3t", Body: map[string]interface{}{
+				"name": "demo", "description": "", "private": true, "visibility": "internal",
+			}},
+		},
+		{
+			kind: KindGitea,
+			base: "/api/v1",
+			opts: CreateRepoOpts{Owner: "bot", Name: "demo", Visibility: VisibilityPublic, Description: "d"},
+			responses: map[string]string{
+				"GET /api/v1/user":        `{"login":"bot"}`,
+				"POST /api/v1/user/repos": `{}`,
+			},
+			want: call{Method: "POST", Path: "/api/v1/user/repos", Auth: "token s3cr3t", Body: map[string]interface{}{
+				"name": "demo", "description": "d", "private": false,
+			}},
+		},
+		{
+			kind: KindGitLab,
+			base: "/api/v4/",
+			opts: CreateRepoOpts{Owner: "acme/team", Name: "demo"},
+			responses: map[string]string{
+				"GET /api/v4/namespaces/acme%2Fteam": `{"id":42}`,
+				"POST /api/v4/projects":              `{}`,
+			},
+			want: call{Method: "POST", Path: "/api/v4/projects", Auth: "Bearer s3cr3t", Body: map[string]interface{}{
+				"name": "demo", "path": "demo", "description": "", "visibility": "private", "namespace_id": float64(42),
+			}},
+		},
+		{
+			kind: KindBitbucket,
+			base: "/rest/api/1.0",
+			opts: CreateRepoOpts{Owner: "scm/PRJ", Name: "demo"},
+			responses: map[string]string{
+				"POST /rest/api/1.0/projects/PRJ/repos": `{}`,
+			},
+			want: call{Method: "POST", Path: "/rest/api/1.0/projects/PRJ/repos", Auth: "Bearer s3cr3t", Body: map[string]interface{}{
+				"name": "demo", "scmId": "git", "public": false,
+			}},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.kind, func(t *testing.T) {
+			srv, calls := fakeServer(t, tc.responses)
+
+			p, err := New(&Opts{Kind: tc.kind, Url: srv.URL + tc.base, Token: "s3cr3t"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := p.CreateRepo(context.Background(), &tc.opts); err != nil {
+				t.Fatal(err)
+			}
+
+			got := (*calls)[len(*calls)-1]
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCreateRepoAlreadyExists(t *testing.T) {
+	srv, calls := fakeServer(t, map[string]string{
+		"GET /user":            `{"login":"bot"}`,
+		"GET /repos/acme/demo": `{}`,
+	})
+
+	p, err := New(&Opts{Kind: KindGitHub, Url: srv.URL, Token: "s3cr3t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.CreateRepo(context.Background(), &CreateRepoOpts{Owner: "acme", Name: "demo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, el := range *calls {
+		if el.Method == http.MethodPost {
+			t.Errorf("unexpected call: %s %s", el.Method, el.Path)
+		}
+	}
+}
+
+func TestRepoFromURL(t *testing.T) {
+	table := []struct {
+		url, owner, name string
+	}{
+		{"https://github.com/acme/demo.git", "acme", "demo"},
+		{"https://gitlab.example.com/acme/team/demo", "acme/team", "demo"},
+		{"https://bitbucket.example.com/scm/PRJ/demo.git", "scm/PRJ", "demo"},
+		{"git@github.com:acme/demo.git", "acme", "demo"},
+	}
+
+	for _, tc := range table {
+		owner, name, err := RepoFromURL(tc.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if owner != tc.owner || name != tc.name {
+			t.Errorf("RepoFromURL(%q) = %q, %q, want %q, %q", tc.url, owner, name, tc.owner, tc.name)
+		}
+	}
+}
+
+func TestOpenPullRequest(t *testing.T) {
+	table := []struct {
+		kind      string
+		base      string
+		opts      PullRequestOpts
+		responses map[string]string
+		want      call
+		wantPR    PullRequest
+	}{
+		{
+			kind: KindGitHub,
+			base: "/",
+			opts: PullRequestOpts{Owner: "acme", Name: "demo", Head: "krateo/42", Base: "main", Title: "t"},
+			responses: map[string]string{
+				"GET /repos/acme/demo/pulls":  `[]`,
+				"POST /repos/acme/demo/pulls": `{"html_url":"https://github.com/acme/demo/pull/1","state":"open"}`,
+			},
+			want: call{Method: "POST", Path: "/repos/acme/demo/pulls", Auth: "Bearer s3cr3t", Body: map[string]interface{}{
+				"title": "t", "head": "krateo/42", "base": "main", "body": "",
+			}},
+			wantPR: PullRequest{Url: "https://github.com/acme/demo/pull/1", State: PullRequestOpen},
+		},
+		{
+			kind: KindGitea,
+			base: "/api/v1",
+			opts: PullRequestOpts{Owner: "acme", Name: "demo", Head: "krateo/42", Base: "main", Title: "t"},
+			responses: map[string]string{
+				"GET /api/v1/repos/acme/demo/pulls":  `[{"html_url":"u0","state":"open","head":{"ref":"other"},"base":{"ref":"main"}}]`,
+				"POST /api/v1/repos/acme/demo/pulls": `{"html_url":"u1","state":"open"}`,
+			},
+			want: call{Method: "POST", Path: "/api/v1/repos/acme/demo/pulls", Auth: "token s3cr3t", Body: map[string]interface{}{
+				"title": "t", "head": "krateo/42", "base": "main", "body": "",
+			}},
+			wantPR: PullRequest{Url: "u1", State: PullRequestOpen},
+		},
+		{
+			kind: KindGitLab,
+			base: "/api/v4",
+			opts: PullRequestOpts{Owner: "acme/team", Name: "demo", Head: "krateo/42", Base: "main", Title: "t"},
+			responses: map[string]string{
+				"GET /api/v4/projects/acme%2Fteam%2Fdemo/merge_requests":  `[{"web_url":"u0","state":"closed"}]`,
+				"POST /api/v4/projects/acme%2Fteam%2Fdemo/merge_requests": `{"web_url":"u1","state":"opened"}`,
+			},
+			want: call{Method: "POST", Path: "/api/v4/projects/acme%2Fteam%2Fdemo/merge_requests", Auth: "Bearer s3cr3t", Body: map[string]interface{}{
+				"source_branch": "krateo/42", "target_branch": "main", "title": "t", "description": "",
+			}},
+			wantPR: PullRequest{Url: "u1", State: PullRequestOpen},
+		},
+		{
+			kind: KindBitbucket,
+			base: "/rest/api/1.0",
+			opts: PullRequestOpts{Owner: "scm/PRJ", Name: "demo", Head: "krateo/42", Base: "main", Title: "t"},
+			responses: map[string]string{
+				"GET /rest/api/1.0/projects/PRJ/repos/demo/pull-requests":  `{"values":[]}`,
+				"POST /rest/api/1.0/projects/PRJ/repos/demo/pull-requests": `{"state":"OPEN","links":{"self":[{"href":"u1"}]}}`,
+			},
+			want: call{Method: "POST", Path: "/rest/api/1.0/projects/PRJ/repos/demo/pull-requests", Auth: "Bearer s3cr3t", Body: map[string]interface{}{
+				"title": "t", "description": "",
+				"fromRef": map[string]interface{}{"id": "refs/heads/krateo/42"},
+				"toRef":   map[string]interface{}{"id": "refs/heads/main"},
+			}},
+			wantPR: PullRequest{Url: "u1", State: PullRequestOpen},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.kind, func(t *testing.T) {
+			srv, calls := fakeServer(t, tc.responses)
+
+			p, err := New(&Opts{Kind: tc.kind, Url: srv.URL + tc.base, Token: "s3cr3t"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pr, err := p.OpenPullRequest(context.Background(), &tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *pr != tc.wantPR {
+				t.Errorf("got %+v, want %+v", *pr, tc.wantPR)
+			}
+
+			got := (*calls)[len(*calls)-1]
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFindPullRequestMerged(t *testing.T) {
+	srv, _ := fakeServer(t, map[string]string{
+		"GET /repos/acme/demo/pulls": `[{"html_url":"u1","state":"closed","merged_at":"2022-03-01T10:00:00Z"}]`,
+	})
+
+	p, err := New(&Opts{Kind: KindGitHub, Url: srv.URL, Token: "s3cr3t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pr, err := p.FindPullRequest(context.Background(), &PullRequestOpts{Owner: "acme", Name: "demo", Head: "krateo/42", Base: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr == nil || pr.State != PullRequestMerged {
+		t.Errorf("got %+v, want state %q", pr, PullRequestMerged)
+	}
+}
+
+func TestFindPullRequestGiteaPages(t *testing.T) {
+	const pageSize = 30
+
+	// a busy repository, with the scaffold pull request on the third page
+	var pulls []map[string]interface{}
+	for i := 0; i < 80; i++ {
+		pulls = append(pulls, map[string]interface{}{
+			"html_url": fmt.Sprintf("u%d", i),
+			"state":    "closed",
+			"head":     map[string]string{"ref": fmt.Sprintf("feature/%d", i)},
+			"base":     map[string]string{"ref": "main"},
+		})
+	}
+	pulls[70]["head"] = map[string]string{"ref": "krateo/42"}
+	pulls[70]["state"] = "open"
+
+	var pages []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		pages = append(pages, r.URL.Query().Get("page"))
+
+		// the server caps the page size below the requested limit
+		start, end := (page-1)*pageSize, page*pageSize
+		if start > len(pulls) {
+			start = len(pulls)
+		}
+		if end > len(pulls) {
+			end = len(pulls)
+		}
+		json.NewEncoder(w).Encode(pulls[start:end])
+	}))
+	defer srv.Close()
+
+	p, err := New(&Opts{Kind: KindGitea, Url: srv.URL + "/api/v1", Token: "s3cr3t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &PullRequestOpts{Owner: "acme", Name: "demo", Head: "krateo/42", Base: "main"}
+	pr, err := p.FindPullRequest(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr == nil || pr.Url != "u70" || pr.State != PullRequestOpen {
+		t.Fatalf("got %+v, want u70 open", pr)
+	}
+	if !reflect.DeepEqual(pages, []string{"1", "2", "3"}) {
+		t.Errorf("got pages %v, want [1 2 3]", pages)
+	}
+
+	pages = nil
+	opts.Head = "krateo/missing"
+	if pr, err := p.FindPullRequest(context.Background(), opts); err != nil || pr != nil {
+		t.Fatalf("got %+v (err: %v), want none", pr, err)
+	}
+	if len(pages) != 4 {
+		t.Errorf("got pages %v, want up to the first empty one", pages)
+	}
+}
@@ -0,0 +1,107 @@
+package clients
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/krateoplatformops/provider-git/apis/v1alpha1"
+	"github.com/krateoplatformops/provider-git/pkg/helpers"
+	"golang.org/x/net/http/httpproxy"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// getCABundle returns the PEM encoded CA certificates referenced by the provider config.
+func getCABundle(ctx context.Context, k client.Client, pc *v1alpha1.ProviderConfig) ([]byte, error) {
+	ref := pc.Spec.CABundleRef
+	if ref == nil {
+		return nil, nil
+	}
+
+	var pem string
+	var err error
+	switch {
+	case ref.ConfigMapKeyRef != nil:
+		pem, err = helpers.GetConfigMapValue(ctx, k, ref.ConfigMapKeyRef)
+	case ref.SecretKeyRef != nil:
+		pem, err = helpers.GetSecret(ctx, k, ref.SecretKeyRef.DeepCopy())
+	default:
+		return nil, fmt.Errorf("no configmap or secret referenced")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !x509.NewCertPool().AppendCertsFromPEM([]byte(pem)) {
+		return nil, fmt.Errorf("no valid PEM encoded certificates found")
+	}
+
+	return []byte(pem), nil
+}
+
+// getProxyFunc returns the function selecting the proxy for each request.
+// Without a proxy url the environment variables are honored.
+func getProxyFunc(ctx context.Context, k client.Client, pc *v1alpha1.ProviderConfig) (func(*http.Request) (*url.URL, error), error) {
+	proxyUrl := helpers.StringValue(pc.Spec.ProxyUrl)
+	if len(proxyUrl) == 0 {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	u, err := url.Parse(proxyUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url: %w", err)
+	}
+	if len(u.Host) == 0 {
+		return nil, fmt.Errorf("invalid proxy url: missing host")
+	}
+
+	if ref := pc.Spec.ProxyCredentialsSecretRef; ref != nil {
+		creds, err := helpers.GetSecret(ctx, k, ref.DeepCopy())
+		if err != nil {
+			return nil, err
+		}
+
+		username, password, ok := strings.Cut(strings.TrimSpace(creds), ":")
+		if !ok {
+			return nil, fmt.Errorf("proxy credentials must be formatted as 'username:password'")
+		}
+		u.User = url.UserPassword(username, password)
+	}
+
+	cfg := &httpproxy.Config{
+		HTTPProxy:  u.String(),
+		HTTPSProxy: u.String(),
+		NoProxy:    strings.Join(pc.Spec.NoProxy, ","),
+	}
+	fn := cfg.ProxyFunc()
+
+	return func(req *http.Request) (*url.URL, error) {
+		return fn(req.URL)
+	}, nil
+}
+
+// newHTTPClient returns an http client that trusts the system
+// and the supplied CA certificates and goes through the proxy.
+func newHTTPClient(caBundle []byte, insecure bool, proxy func(*http.Request) (*url.URL, error)) *http.Client {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.Proxy = proxy
+	tr.TLSClientConfig = &tls.Config{
+		InsecureSkipVerify: insecure,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if len(caBundle) > 0 {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		rootCAs.AppendCertsFromPEM(caBundle)
+		tr.TLSClientConfig.RootCAs = rootCAs
+	}
+
+	return &http.Client{Transport: tr}
+}
@@ -4,18 +4,50 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/go-git/go-git/v5/plumbing/format/gitattributes"
 	"github.com/krateoplatformops/provider-git/pkg/clients/git"
 	gi "github.com/sabhiram/go-gitignore"
 )
 
+// Template control files, read from the template repository root and
+// never copied.
+const (
+	// IgnoreFile lists the paths copied verbatim, without rendering.
+	IgnoreFile = ".krateoignore"
+	// ExcludeFile lists the paths never copied.
+	ExcludeFile = ".krateoexclude"
+	// RulesFile holds the conditional inclusion Rules.
+	RulesFile = ".krateorules"
+)
+
 type CopyOpts struct {
 	FromRepo   *git.Repo
 	ToRepo     *git.Repo
 	RenderFunc func(in io.Reader, out io.Writer) error
-	Ignore     *gi.GitIgnore
+	// RenderNameFunc, if set, renders the file and directory names and the
+	// symlink targets, one path segment at a time, as plain text: unlike
+	// the RenderFunc it must not escape the values.
+	RenderNameFunc func(name string) (string, error)
+	Ignore         *gi.GitIgnore
+	// Exclude, if set, matches the paths never copied.
+	Exclude *gi.GitIgnore
+	// Attributes, if set, match the gitattributes deciding whether the
+	// files are rendered.
+	Attributes gitattributes.Matcher
+	// Rules, if evaluated, exclude the matching paths from the copy.
+	Rules Rules
+	// RuleExcluded, if set, is called for each path excluded by a rule.
+	RuleExcluded func(path string, rule *Rule)
+	// SymlinkRejected, if set, is called for each symlink not copied
+	// since its target (as rendered) is absolute or resolves outside the
+	// copied tree.
+	SymlinkRejected func(path, target string)
 }
 
 func (cfg *CopyOpts) WriteBytes(src []byte, dstfn string) (err error) {
@@ -34,16 +66,30 @@ func (cfg *CopyOpts) WriteBytes(src []byte, dstfn string) (err error) {
 	return
 }
 
+// CopyFile copies, and renders unless doNotRender, the file preserving its permission bits.
 func (cfg *CopyOpts) CopyFile(src, dst string, doNotRender bool) (err error) {
 	fromFS, toFS := cfg.FromRepo.FS(), cfg.ToRepo.FS()
 
+	si, err := fromFS.Stat(src)
+	if err != nil {
+		return err
+	}
+	mode := si.Mode().Perm()
+
 	in, err := fromFS.Open(src)
 	if err != nil {
 		return err
 	}
 	defer in.Close()
 
-	out, err := toFS.Create(dst)
+	// an existing file keeps its mode when opened
+	if di, err := toFS.Lstat(dst); err == nil && di.Mode() != mode {
+		if err := toFS.Remove(dst); err != nil {
+			return err
+		}
+	}
+
+	out, err := toFS.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode)
 	if err != nil {
 		return err
 	}
@@ -59,13 +105,60 @@ func (cfg *CopyOpts) CopyFile(src, dst string, doNotRender bool) (err error) {
 		return err
 	}
 
-	return cfg.RenderFunc(in, out)
+	bin, err := ioutil.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	if !cfg.renderable(src, bin) {
+		_, err = out.Write(bin)
+		return err
+	}
+
+	return cfg.RenderFunc(bytes.NewReader(bin), out)
+}
+
+// renderable tells if the file is rendered: the 'render' attribute, the
+// 'binary' one or the 'text' one decide, otherwise the content is
+// rendered unless binary.
+func (cfg *CopyOpts) renderable(path string, content []byte) bool {
+	if cfg.Attributes != nil {
+		segments := strings.Split(strings.Trim(filepath.ToSlash(path), "/"), "/")
+
+		attrs, _ := cfg.Attributes.Match(segments, []string{"render", "binary", "text"})
+		if attr, ok := attrs["render"]; ok && (attr.IsSet() || attr.IsUnset()) {
+			return attr.IsSet()
+		}
+		if attr, ok := attrs["binary"]; ok && attr.IsSet() {
+			return false
+		}
+		if attr, ok := attrs["text"]; ok && (attr.IsSet() || attr.IsUnset()) {
+			return attr.IsSet()
+		}
+	}
+
+	return !isBinary(content)
+}
+
+// isBinary tells if the content holds a NUL byte in its first 8000 bytes,
+// like git does, or is not valid UTF-8.
+func isBinary(content []byte) bool {
+	head := content
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+
+	return bytes.IndexByte(head, 0) >= 0 || !utf8.Valid(content)
 }
 
 // CopyDir recursively copies a directory tree, attempting to preserve permissions.
 // Source directory must exist, destination directory must *not* exist.
-// Symlinks are ignored and skipped.
-func (cfg *CopyOpts) CopyDir(src, dst string) (err error) {
+// The control files and the paths matched by Exclude or excluded by the
+// Rules are skipped; names and symlink targets are rendered like the file
+// contents, unless ignored. Symlinks are recreated if they resolve inside
+// both the source and the destination trees; '.git' entries are ignored and
+// skipped.
+func (cfg *CopyOpts) CopyDir(src, dst string) error {
 	if len(src) == 0 {
 		src = "/"
 	}
@@ -74,11 +167,15 @@ func (cfg *CopyOpts) CopyDir(src, dst string) (err error) {
 		dst = "/"
 	}
 
-	fromFS, toFS := cfg.FromRepo.FS(), cfg.ToRepo.FS()
-
 	src = filepath.Clean(src)
 	dst = filepath.Clean(dst)
 
+	return cfg.copyDir(src, dst, src, dst)
+}
+
+func (cfg *CopyOpts) copyDir(src, dst, srcRoot, dstRoot string) (err error) {
+	fromFS, toFS := cfg.FromRepo.FS(), cfg.ToRepo.FS()
+
 	si, err := fromFS.Stat(src)
 	if err != nil {
 		return err
@@ -98,26 +195,63 @@ func (cfg *CopyOpts) CopyDir(src, dst string) (err error) {
 	}
 
 	for _, entry := range entries {
+		// Skip git metadata (i.e. the submodules '.git' files).
+		if entry.Name() == ".git" {
+			continue
+		}
+
 		srcPath := filepath.Join(src, entry.Name())
-		dstPath := filepath.Join(dst, entry.Name())
 
+		// a trailing slash lets the 'dir/' globs match the directories
+		matchPath := srcPath
 		if entry.IsDir() {
-			err = cfg.CopyDir(srcPath, dstPath)
+			matchPath += "/"
+		}
+
+		if isControlFile(srcPath) {
+			continue
+		}
+
+		if cfg.Exclude != nil && cfg.Exclude.MatchesPath(matchPath) {
+			continue
+		}
+
+		if rule := cfg.Rules.Excluding(matchPath); rule != nil {
+			if cfg.RuleExcluded != nil {
+				cfg.RuleExcluded(srcPath, rule)
+			}
+			continue
+		}
+
+		// ignore file eventually
+		var doNotRender bool
+		if cfg.Ignore != nil {
+			if cfg.Ignore.MatchesPath(srcPath) {
+				doNotRender = true
+			}
+		}
+
+		name := entry.Name()
+		if !doNotRender {
+			name, err = cfg.renderName(name)
+			if err != nil {
+				return
+			}
+		}
+		dstPath := filepath.Join(dst, name)
+
+		if entry.IsDir() {
+			err = cfg.copyDir(srcPath, dstPath, srcRoot, dstRoot)
 			if err != nil {
 				return
 			}
 		} else {
-			// Skip symlinks.
 			if entry.Mode()&os.ModeSymlink != 0 {
-				continue
-			}
-
-			// ignore file eventually
-			var doNotRender bool
-			if cfg.Ignore != nil {
-				if cfg.Ignore.MatchesPath(srcPath) {
-					doNotRender = true
+				err = cfg.copySymlink(srcPath, dstPath, srcRoot, dstRoot, doNotRender)
+				if err != nil {
+					return
 				}
+				continue
 			}
 
 			// do the copy
@@ -130,3 +264,120 @@ func (cfg *CopyOpts) CopyDir(src, dst string) (err error) {
 
 	return
 }
+
+// isControlFile tells if the path is one of the template control files.
+func isControlFile(path string) bool {
+	switch strings.TrimPrefix(path, string(filepath.Separator)) {
+	case IgnoreFile, ExcludeFile, RulesFile:
+		return true
+	}
+
+	return false
+}
+
+// renderName renders the file or directory name with the RenderNameFunc;
+// the result must be a single path segment other than '.', '..' or '.git'.
+func (cfg *CopyOpts) renderName(name string) (string, error) {
+	if cfg.RenderNameFunc == nil {
+		return name, nil
+	}
+
+	res, err := cfg.RenderNameFunc(name)
+	if err != nil {
+		return "", fmt.Errorf("rendering name '%s': %w", name, err)
+	}
+
+	res = strings.TrimSpace(res)
+	switch {
+	case len(res) == 0:
+		return "", fmt.Errorf("name '%s' renders to an empty path", name)
+	case strings.ContainsAny(res, "/"+string(filepath.Separator)):
+		return "", fmt.Errorf("name '%s' renders to '%s', holding a path separator", name, res)
+	case res == "." || res == "..":
+		return "", fmt.Errorf("name '%s' renders to '%s', outside of the destination", name, res)
+	case res == ".git":
+		return "", fmt.Errorf("name '%s' renders to '%s', a git metadata path", name, res)
+	}
+
+	return res, nil
+}
+
+// renderTarget renders the symlink target segment by segment, like the
+// names; the empty, '.' and '..' segments are kept as they are.
+func (cfg *CopyOpts) renderTarget(target string) (string, error) {
+	segments := strings.Split(target, "/")
+	for i, el := range segments {
+		switch el {
+		case "", ".", "..":
+			continue
+		}
+
+		res, err := cfg.renderName(el)
+		if err != nil {
+			return "", fmt.Errorf("symlink target '%s': %w", target, err)
+		}
+		segments[i] = res
+	}
+
+	return strings.Join(segments, "/"), nil
+}
+
+// copySymlink recreates the symlink with its target rendered like the names,
+// unless ignored, and skips it if the target is absolute or resolves outside
+// the source tree (as written) or the destination tree (as rendered).
+func (cfg *CopyOpts) copySymlink(src, dst, srcRoot, dstRoot string, doNotRender bool) error {
+	fromFS, toFS := cfg.FromRepo.FS(), cfg.ToRepo.FS()
+
+	target, err := fromFS.Readlink(src)
+	if err != nil {
+		return err
+	}
+
+	rendered := target
+	if !doNotRender {
+		rendered, err = cfg.renderTarget(target)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !resolvesWithin(srcRoot, src, target) {
+		if cfg.SymlinkRejected != nil {
+			cfg.SymlinkRejected(src, target)
+		}
+		return nil
+	}
+
+	if !resolvesWithin(dstRoot, dst, rendered) {
+		if cfg.SymlinkRejected != nil {
+			cfg.SymlinkRejected(src, rendered)
+		}
+		return nil
+	}
+
+	if _, err := toFS.Lstat(dst); err == nil {
+		if err := toFS.Remove(dst); err != nil {
+			return err
+		}
+	}
+
+	return toFS.Symlink(rendered, dst)
+}
+
+// resolvesWithin tells if the relative target of the link resolves inside
+// root; the link directory is made relative first, since joining to the
+// root '/' would clean away the leading '..' elements.
+func resolvesWithin(root, link, target string) bool {
+	if filepath.IsAbs(target) {
+		return false
+	}
+
+	dir, err := filepath.Rel(root, filepath.Dir(link))
+	if err != nil {
+		return false
+	}
+
+	res := filepath.Join(dir, target)
+
+	return res != ".." && !strings.HasPrefix(res, ".."+string(filepath.Separator))
+}
@@ -2,15 +2,39 @@ package repo
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/cbroglie/mustache"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/gitattributes"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/krateoplatformops/provider-git/pkg/clients/git"
+	gi "github.com/sabhiram/go-gitignore"
 )
 
+func TestMain(m *testing.M) {
+	// the test repositories are created in temporary directories
+	if err := git.AllowLocalRepos(os.TempDir()); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
 func TestRenderFunc(t *testing.T) {
 	dat, err := ioutil.ReadFile("../../../testdata/values.json")
 	if err != nil {
@@ -53,3 +77,494 @@ func createRenderer(values map[string]interface{}) func(in io.Reader, out io.Wri
 		return tmpl.FRender(out, values)
 	}
 }
+
+func createNameRenderer(values map[string]interface{}) func(name string) (string, error) {
+	return func(name string) (string, error) {
+		return mustache.RenderRaw(name, true, values)
+	}
+}
+
+// fixture is a template repository copied by copyFixture.
+type fixture struct {
+	from  *git.Repo
+	src   string
+	modes map[string]os.FileMode
+	links map[string]string
+	opts  []func(co *CopyOpts)
+}
+
+type fixtureOpt func(*fixture)
+
+// fromTemplate copies the cloned template instead of a new one.
+func fromTemplate(repo *git.Repo) fixtureOpt {
+	return func(f *fixture) { f.from = repo }
+}
+
+// fromDir copies the template directory instead of 'skel'.
+func fromDir(src string) fixtureOpt {
+	return func(f *fixture) { f.src = src }
+}
+
+// withModes creates the template files with the modes, instead of 0644.
+func withModes(modes map[string]os.FileMode) fixtureOpt {
+	return func(f *fixture) { f.modes = modes }
+}
+
+// withSymlinks adds the template symlinks (path to target).
+func withSymlinks(links map[string]string) fixtureOpt {
+	return func(f *fixture) { f.links = links }
+}
+
+// withCopyOpts sets the copy options, once the repositories are created.
+func withCopyOpts(fn func(co *CopyOpts)) fixtureOpt {
+	return func(f *fixture) { f.opts = append(f.opts, fn) }
+}
+
+// copyFixture copies a template repository holding the files (content by
+// path) to the root of a new repository, then pushes and clones it back so
+// that the result tells what git recorded (i.e. the modes and symlinks).
+func copyFixture(t *testing.T, files map[string]string, opts ...fixtureOpt) *git.Repo {
+	t.Helper()
+
+	ctx := context.Background()
+
+	fx := &fixture{src: "skel"}
+	for _, fn := range opts {
+		fn(fx)
+	}
+
+	fromRepo := fx.from
+	if fromRepo == nil {
+		var err error
+		if fromRepo, err = git.Init(&git.CloneOpts{URL: "file:///template.git"}, "main"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fs := fromRepo.FS()
+	for name, content := range files {
+		mode, ok := fx.modes[name]
+		if !ok {
+			mode = 0644
+		}
+
+		f, err := fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+		f.Close()
+	}
+
+	for link, target := range fx.links {
+		if err := fs.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := filepath.Join(t.TempDir(), "target.git")
+	if _, err := gogit.PlainInit(dir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	toRepo, err := git.Init(&git.CloneOpts{URL: dir}, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	co := &CopyOpts{FromRepo: fromRepo, ToRepo: toRepo}
+	for _, fn := range fx.opts {
+		fn(co)
+	}
+
+	if err := co.CopyDir(fx.src, "/"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := toRepo.Commit(".", &git.CommitOpts{Message: "scaffold"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := toRepo.Push(ctx, &git.PushOpts{RemoteName: "origin", Branch: "main"}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := git.Clone(ctx, &git.CloneOpts{URL: dir, Ref: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+// readFile returns the content of the file, failing the test if missing.
+func readFile(t *testing.T, repo *git.Repo, name string) string {
+	t.Helper()
+
+	f, err := repo.FS().Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	res, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(res)
+}
+
+func TestCopyDirPreservesModes(t *testing.T) {
+	modes := map[string]os.FileMode{
+		"skel/scripts/bootstrap.sh": 0755,
+		"skel/README.md":            0644,
+	}
+
+	res := copyFixture(t, map[string]string{
+		"skel/scripts/bootstrap.sh": "{{name}}",
+		"skel/README.md":            "{{name}}",
+	}, withModes(modes), withCopyOpts(func(co *CopyOpts) {
+		co.RenderFunc = createRenderer(map[string]interface{}{"name": "demo"})
+	}))
+
+	for name, mode := range modes {
+		dst := strings.TrimPrefix(name, "skel/")
+		fi, err := res.FS().Stat(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != mode {
+			t.Errorf("%s: got mode %v, want %v", dst, fi.Mode().Perm(), mode)
+		}
+	}
+}
+
+func TestCopyDirSymlinks(t *testing.T) {
+	links := map[string]string{
+		"skel/docs/latest.md": "v1.md",
+		"skel/current.md":     "docs/v1.md",
+		"skel/secret.md":      "../secret.md",
+		"skel/passwd":         "/etc/passwd",
+	}
+
+	rejected := map[string]string{}
+	res := copyFixture(t, map[string]string{
+		"skel/docs/v1.md": "content",
+		"secret.md":       "content",
+	}, withSymlinks(links), withCopyOpts(func(co *CopyOpts) {
+		co.SymlinkRejected = func(path, target string) {
+			rejected[path] = target
+		}
+	}))
+
+	if len(rejected) != 2 || rejected["skel/secret.md"] != "../secret.md" || rejected["skel/passwd"] != "/etc/passwd" {
+		t.Fatalf("unexpected rejected symlinks: %v", rejected)
+	}
+
+	for _, el := range []string{"docs/latest.md", "current.md"} {
+		got, err := res.FS().Readlink(el)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := links["skel/"+el]; got != want {
+			t.Errorf("%s: got target %q, want %q", el, got, want)
+		}
+	}
+
+	for _, el := range []string{"secret.md", "passwd"} {
+		if _, err := res.FS().Lstat(el); !os.IsNotExist(err) {
+			t.Errorf("%s: expected not to be copied (err: %v)", el, err)
+		}
+	}
+}
+
+// newSubmoduleRemote returns a bare repository holding the files and the
+// submodules (path to url) at the HEAD commit of their repositories.
+func newSubmoduleRemote(t *testing.T, files map[string]string, submodules map[string]string) string {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "remote.git")
+	remote, err := gogit.PlainInit(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head := plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/main")
+	if err := remote.Storer.SetReference(head); err != nil {
+		t.Fatal(err)
+	}
+
+	work, err := gogit.PlainInit(t.TempDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wt, err := work.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var modules strings.Builder
+	for path, url := range submodules {
+		fmt.Fprintf(&modules, "[submodule %q]\n\tpath = %s\n\turl = %s\n", path, path, url)
+	}
+
+	for name, content := range files {
+		if err := util.WriteFile(wt.Filesystem, name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(submodules) > 0 {
+		if err := util.WriteFile(wt.Filesystem, ".gitmodules", []byte(modules.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := wt.AddWithOptions(&gogit.AddOptions{All: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the gitlinks, as 'git submodule add' would record them
+	idx, err := work.Storer.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, url := range submodules {
+		sub, err := gogit.PlainOpen(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref, err := sub.Head()
+		if err != nil {
+			t.Fatal(err)
+		}
+		idx.Entries = append(idx.Entries, &index.Entry{Name: path, Hash: ref.Hash(), Mode: filemode.Submodule})
+	}
+	if err := work.Storer.SetIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = wt.Commit("first", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := work.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{dir}}); err != nil {
+		t.Fatal(err)
+	}
+	err = work.Push(&gogit.PushOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{"refs/heads/master:refs/heads/main"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func TestCopyDirSubmodules(t *testing.T) {
+	ctx := context.Background()
+
+	nested := newSubmoduleRemote(t, map[string]string{"deep.txt": "{{name}}"}, nil)
+	lib := newSubmoduleRemote(t, map[string]string{"lib.txt": "{{name}}"}, map[string]string{"vendor": nested})
+	template := newSubmoduleRemote(t, map[string]string{"skel/README.md": "# {{name}}"}, map[string]string{"skel/lib": lib})
+
+	fromRepo, err := git.Clone(ctx, &git.CloneOpts{URL: template, Ref: "main", RecurseSubmodules: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := copyFixture(t, nil, fromTemplate(fromRepo), withCopyOpts(func(co *CopyOpts) {
+		co.RenderFunc = createRenderer(map[string]interface{}{"name": "demo"})
+	}))
+
+	want := map[string]string{
+		"README.md":           "# demo",
+		"lib/lib.txt":         "demo",
+		"lib/vendor/deep.txt": "demo",
+	}
+	for name, content := range want {
+		if got := readFile(t, res, name); got != content {
+			t.Errorf("%s: got %q, want %q", name, got, content)
+		}
+	}
+
+	// the submodules '.git' files are not copied
+	for _, el := range []string{"lib/.git", "lib/vendor/.git"} {
+		if _, err := res.FS().Lstat(el); !os.IsNotExist(err) {
+			t.Errorf("%s: expected not to be copied (err: %v)", el, err)
+		}
+	}
+
+	// without recursion the submodules are empty directories
+	fromRepo, err = git.Clone(ctx, &git.CloneOpts{URL: template, Ref: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fromRepo.FS().Stat("skel/lib/lib.txt"); !os.IsNotExist(err) {
+		t.Errorf("expected the submodule not to be checked out (err: %v)", err)
+	}
+}
+
+func TestCopyDirRendersSymlinkTargets(t *testing.T) {
+	values := map[string]interface{}{"name": "R&D's app"}
+
+	res := copyFixture(t, map[string]string{
+		"skel/charts/{{name}}/Chart.yaml": "name: {{name}}",
+	}, withSymlinks(map[string]string{
+		"skel/chart.yaml":    "charts/{{name}}/Chart.yaml",
+		"skel/verbatim.yaml": "charts/{{name}}/Chart.yaml",
+	}), withCopyOpts(func(co *CopyOpts) {
+		co.RenderFunc = createRenderer(values)
+		co.RenderNameFunc = createNameRenderer(values)
+		co.Ignore = gi.CompileIgnoreLines("verbatim.yaml")
+	}))
+
+	targets := map[string]string{
+		"chart.yaml":    "charts/R&D's app/Chart.yaml",
+		"verbatim.yaml": "charts/{{name}}/Chart.yaml",
+	}
+	for name, want := range targets {
+		got, err := res.FS().Readlink(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: got target %q, want %q", name, got, want)
+		}
+	}
+
+	co := &CopyOpts{
+		RenderNameFunc: createNameRenderer(map[string]interface{}{
+			"name": "demo", "up": "..", "nested": "a/b", "dotgit": ".git",
+		}),
+	}
+
+	table := []struct {
+		target string
+		want   string
+	}{
+		{"../{{name}}/./values.yaml", "../demo/./values.yaml"},
+		{"/etc/{{name}}", "/etc/demo"},
+		{"{{up}}/secret.md", ""},
+		{"{{nested}}/values.yaml", ""},
+		{"{{dotgit}}/config", ""},
+	}
+	for _, tc := range table {
+		got, err := co.renderTarget(tc.target)
+		if len(tc.want) == 0 {
+			if err == nil {
+				t.Errorf("%s: expected error, got %q", tc.target, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %q (err: %v), want %q", tc.target, got, err, tc.want)
+		}
+	}
+}
+
+func TestCopyDirRendersNames(t *testing.T) {
+	values := map[string]interface{}{"name": "R&D's app", "component": "api"}
+
+	res := copyFixture(t, map[string]string{
+		"skel/src/{{component}}/main.go":  "name: {{component}}",
+		"skel/charts/{{name}}/Chart.yaml": "name: {{component}}",
+	}, withCopyOpts(func(co *CopyOpts) {
+		co.RenderFunc = createRenderer(values)
+		co.RenderNameFunc = createNameRenderer(values)
+	}))
+
+	// the names are not HTML escaped
+	for _, el := range []string{"src/api/main.go", "charts/R&D's app/Chart.yaml"} {
+		if got := readFile(t, res, el); got != "name: api" {
+			t.Errorf("%s: got %q, want %q", el, got, "name: api")
+		}
+	}
+
+	names := map[string]bool{
+		"{{name}}":        true,
+		"{{name}}.yaml":   true,
+		"{{missing}}":     false,
+		"{{up}}":          false,
+		"{{up}}/../..":    false,
+		"{{abs}}":         false,
+		"{{nested}}":      false,
+		"a/../{{dotgit}}": false,
+		"{{dotgit}}":      false,
+	}
+	co := &CopyOpts{
+		RenderNameFunc: createNameRenderer(map[string]interface{}{
+			"name": "demo", "up": "..", "abs": "/etc", "nested": "a/b", "dotgit": ".git",
+		}),
+	}
+	for name, ok := range names {
+		_, err := co.renderName(name)
+		if ok != (err == nil) {
+			t.Errorf("%s: unexpected result (err: %v)", name, err)
+		}
+	}
+}
+
+func TestCopyDirExcludes(t *testing.T) {
+	files := map[string]string{}
+	for _, el := range []string{IgnoreFile, ExcludeFile, RulesFile, "docs/TEMPLATE.md", "docs/usage.md", "testdata/fixture.json", "main.go"} {
+		files[el] = "content"
+	}
+
+	res := copyFixture(t, files, fromDir("/"), withCopyOpts(func(co *CopyOpts) {
+		co.Exclude = gi.CompileIgnoreLines("docs/TEMPLATE.md", "testdata/")
+	}))
+
+	for _, el := range []string{"docs/usage.md", "main.go"} {
+		if _, err := res.FS().Stat(el); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, el := range []string{IgnoreFile, ExcludeFile, RulesFile, "docs/TEMPLATE.md", "testdata"} {
+		if _, err := res.FS().Stat(el); err == nil {
+			t.Errorf("%s: expected not to be copied", el)
+		}
+	}
+}
+
+func TestCopyDirSkipsRenderingBinaries(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00{{name}}"
+	latin1 := "caf\xe9 {{name}}"
+
+	res := copyFixture(t, map[string]string{
+		".gitattributes":          "*.tpl -text\nforced.bin render\n",
+		"skel/logo.png":           png,
+		"skel/latin1.txt":         latin1,
+		"skel/README.md":          "# {{name}}",
+		"skel/verbatim.tpl":       "{{name}}",
+		"skel/forced.bin":         "{{name}}",
+		"skel/sub/notes.txt":      "{{name}}",
+		"skel/sub/.gitattributes": "notes.txt binary\n",
+	}, withCopyOpts(func(co *CopyOpts) {
+		attrs, err := gitattributes.ReadPatterns(co.FromRepo.FS(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		co.Attributes = gitattributes.NewMatcher(attrs)
+		co.RenderFunc = createRenderer(map[string]interface{}{"name": "demo"})
+	}))
+
+	want := map[string]string{
+		"logo.png":      png,
+		"latin1.txt":    latin1,
+		"README.md":     "# demo",
+		"verbatim.tpl":  "{{name}}",
+		"forced.bin":    "demo",
+		"sub/notes.txt": "{{name}}",
+	}
+	for name, content := range want {
+		if got := readFile(t, res, name); got != content {
+			t.Errorf("%s: got %q, want %q", name, got, content)
+		}
+	}
+}
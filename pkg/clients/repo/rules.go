@@ -0,0 +1,492 @@
+package repo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	gi "github.com/sabhiram/go-gitignore"
+)
+
+// Rule copies the paths matching the glob only when the condition holds.
+type Rule struct {
+	// Glob: the path pattern, with the '.krateoignore' (gitignore) syntax.
+	Glob string
+	// Condition: the boolean expression evaluated against the values.
+	Condition string
+	// Include: the last evaluation of the condition.
+	Include bool
+
+	matcher *gi.GitIgnore
+	cond    expr
+}
+
+// Rules are the conditional inclusion rules of a template, one per line:
+//
+//	# glob         condition
+//	Dockerfile     containerized
+//	terraform/     cloud != "none" && !legacy
+//
+// A condition compares (==, !=, <, <=, >, >=) and combines (!, &&, ||, and
+// parentheses) dotted value names, strings, numbers, true, false and null;
+// a value alone holds unless missing, false, zero or empty. Names start with
+// a letter (of any script) or '_', then hold letters, digits, '_' and '-';
+// strings are single or double quoted, with the Go escape sequences.
+type Rules []*Rule
+
+// ParseRules reads the rules, skipping blank and '#' comment lines.
+func ParseRules(r io.Reader) (Rules, error) {
+	var res Rules
+
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		idx := strings.IndexFunc(line, unicode.IsSpace)
+		if idx < 0 {
+			return nil, fmt.Errorf("line %d: missing condition for '%s'", n, line)
+		}
+
+		glob, cond := line[:idx], strings.TrimSpace(line[idx:])
+
+		fn, err := parseExpr(cond)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		res = append(res, &Rule{
+			Glob:      glob,
+			Condition: cond,
+			matcher:   gi.CompileIgnoreLines(glob),
+			cond:      fn,
+		})
+	}
+
+	return res, sc.Err()
+}
+
+// Evaluate evaluates the rules conditions against the values.
+func (rs Rules) Evaluate(values map[string]interface{}) error {
+	for _, el := range rs {
+		val, err := el.cond(values)
+		if err != nil {
+			return fmt.Errorf("evaluating '%s': %w", el.Condition, err)
+		}
+		el.Include = truthy(val)
+	}
+
+	return nil
+}
+
+// Excluding returns the first evaluated rule excluding the path, if any.
+func (rs Rules) Excluding(path string) *Rule {
+	for _, el := range rs {
+		if !el.Include && el.matcher.MatchesPath(path) {
+			return el
+		}
+	}
+
+	return nil
+}
+
+// expr is a compiled expression.
+type expr func(values map[string]interface{}) (interface{}, error)
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenOp
+	tokenName
+	tokenLiteral
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	val  interface{}
+}
+
+// parseExpr compiles the boolean expression.
+func parseExpr(s string) (expr, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	res, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEnd {
+		return nil, fmt.Errorf("unexpected '%s' in '%s'", tok.text, s)
+	}
+
+	return res, nil
+}
+
+func tokenize(s string) ([]token, error) {
+	var res []token
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+
+		case strings.HasPrefix(s[i:], "&&"), strings.HasPrefix(s[i:], "||"),
+			strings.HasPrefix(s[i:], "=="), strings.HasPrefix(s[i:], "!="),
+			strings.HasPrefix(s[i:], "<="), strings.HasPrefix(s[i:], ">="):
+			res = append(res, token{kind: tokenOp, text: s[i : i+2]})
+			i += 2
+
+		case strings.IndexByte("()!<>", c) >= 0:
+			res = append(res, token{kind: tokenOp, text: s[i : i+1]})
+			i++
+
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(s) && s[end] != c {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated string in '%s'", s)
+			}
+
+			text := s[i : end+1]
+			val, err := unquote(text[1:len(text)-1], c)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", text)
+			}
+			res = append(res, token{kind: tokenLiteral, text: text, val: val})
+			i = end + 1
+
+		case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(s) && strings.IndexByte("0123456789.eE+-", s[end]) >= 0 {
+				end++
+			}
+
+			text := s[i:end]
+			val, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number '%s'", text)
+			}
+			res = append(res, token{kind: tokenLiteral, text: text, val: val})
+			i = end
+
+		case c == '_' || isNameStart(s[i:]):
+			end := i + 1
+			for end < len(s) {
+				r, size := utf8.DecodeRuneInString(s[end:])
+				if r != '_' && r != '-' && r != '.' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				end += size
+			}
+
+			text := s[i:end]
+			switch text {
+			case "true":
+				res = append(res, token{kind: tokenLiteral, text: text, val: true})
+			case "false":
+				res = append(res, token{kind: tokenLiteral, text: text, val: false})
+			case "null":
+				res = append(res, token{kind: tokenLiteral, text: text, val: nil})
+			default:
+				res = append(res, token{kind: tokenName, text: text})
+			}
+			i = end
+
+		default:
+			r, _ := utf8.DecodeRuneInString(s[i:])
+			return nil, fmt.Errorf("unexpected '%c' in '%s'", r, s)
+		}
+	}
+
+	return append(res, token{kind: tokenEnd, text: "end of expression"}), nil
+}
+
+// isNameStart tells if s starts with a letter, of any script.
+func isNameStart(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsLetter(r)
+}
+
+// unquote unescapes the content of a string quoted by quote, with the Go
+// escape sequences for both the single and the double quotes.
+func unquote(s string, quote byte) (string, error) {
+	var b strings.Builder
+	for len(s) > 0 {
+		r, multibyte, tail, err := strconv.UnquoteChar(s, quote)
+		if err != nil {
+			return "", err
+		}
+		if r < utf8.RuneSelf || !multibyte {
+			b.WriteByte(byte(r))
+		} else {
+			b.WriteRune(r)
+		}
+		s = tail
+	}
+
+	return b.String(), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEnd {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) isOp(text string) bool {
+	tok := p.peek()
+	return tok.kind == tokenOp && tok.text == text
+}
+
+// or := and ('||' and)*
+func (p *parser) or() (expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOp("||") {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = logical(left, right, true)
+	}
+
+	return left, nil
+}
+
+// and := unary ('&&' unary)*
+func (p *parser) and() (expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOp("&&") {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = logical(left, right, false)
+	}
+
+	return left, nil
+}
+
+// unary := '!' unary | comparison
+func (p *parser) unary() (expr, error) {
+	if !p.isOp("!") {
+		return p.comparison()
+	}
+
+	p.next()
+	operand, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(values map[string]interface{}) (interface{}, error) {
+		val, err := operand(values)
+		if err != nil {
+			return nil, err
+		}
+		return !truthy(val), nil
+	}, nil
+}
+
+// comparison := primary (('==' | '!=' | '<' | '<=' | '>' | '>=') primary)?
+func (p *parser) comparison() (expr, error) {
+	left, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	if tok.kind != tokenOp {
+		return left, nil
+	}
+	switch tok.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.next()
+	default:
+		return left, nil
+	}
+
+	right, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(values map[string]interface{}) (interface{}, error) {
+		lv, err := left(values)
+		if err != nil {
+			return nil, err
+		}
+		rv, err := right(values)
+		if err != nil {
+			return nil, err
+		}
+		return compare(tok.text, lv, rv)
+	}, nil
+}
+
+// primary := '(' or ')' | literal | name
+func (p *parser) primary() (expr, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenLiteral:
+		return func(map[string]interface{}) (interface{}, error) {
+			return tok.val, nil
+		}, nil
+
+	case tokenName:
+		path := strings.Split(tok.text, ".")
+		return func(values map[string]interface{}) (interface{}, error) {
+			return lookup(values, path), nil
+		}, nil
+
+	case tokenOp:
+		if tok.text == "(" {
+			res, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			if !p.isOp(")") {
+				return nil, fmt.Errorf("expected ')' before %s", p.peek().text)
+			}
+			p.next()
+			return res, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected %s", tok.text)
+}
+
+func logical(left, right expr, or bool) expr {
+	return func(values map[string]interface{}) (interface{}, error) {
+		lv, err := left(values)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(lv) == or {
+			return or, nil
+		}
+
+		rv, err := right(values)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(rv), nil
+	}
+}
+
+// lookup returns the value at the dotted path, nil if missing.
+func lookup(values map[string]interface{}, path []string) interface{} {
+	var res interface{} = values
+	for _, el := range path {
+		m, ok := res.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		res = m[el]
+	}
+
+	return res
+}
+
+func compare(op string, lv, rv interface{}) (interface{}, error) {
+	switch op {
+	case "==":
+		return reflect.DeepEqual(lv, rv), nil
+	case "!=":
+		return !reflect.DeepEqual(lv, rv), nil
+	}
+
+	var cmp int
+	switch l := lv.(type) {
+	case float64:
+		r, ok := rv.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %v %s %v", lv, op, rv)
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	case string:
+		r, ok := rv.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %v %s %v", lv, op, rv)
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return nil, fmt.Errorf("cannot compare %v %s %v", lv, op, rv)
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// truthy tells if the value holds: it is not missing, false, zero or empty.
+func truthy(val interface{}) bool {
+	switch v := val.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return len(v) > 0
+	case float64:
+		return v != 0
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+
+	return true
+}
@@ -0,0 +1,117 @@
+package repo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseExpr(t *testing.T) {
+	values := map[string]interface{}{
+		"containerized": true,
+		"cloud":         "aws",
+		"replicas":      float64(3),
+		"name":          "",
+		"db":            map[string]interface{}{"engine": "postgres", "enabled": false},
+		"tags":          []interface{}{"a"},
+		"città":         "Roma",
+		"note":          "it's \"quoted\"",
+	}
+
+	table := []struct {
+		expr string
+		want bool
+	}{
+		{`containerized`, true},
+		{`!containerized`, false},
+		{`missing`, false},
+		{`name`, false},
+		{`tags`, true},
+		{`cloud != "none"`, true},
+		{`cloud == 'aws' && replicas >= 3`, true},
+		{`replicas > 3 || db.engine == "postgres"`, true},
+		{`db.enabled || !(cloud == "aws")`, false},
+		{`db.missing.deep == null`, true},
+		{`replicas < 10 && replicas <= 3 && replicas != 2.5`, true},
+		{`cloud < "gcp"`, true},
+		{`!!containerized && true && !false`, true},
+		// names in any script
+		{`città == "Roma"`, true},
+		{`!città_2`, true},
+		// both quote styles unescape the same way
+		{`note == 'it\'s "quoted"'`, true},
+		{`note == "it's \"quoted\""`, true},
+		{`'caf\u00e9\t' == "café\t"`, true},
+		{`'a\\b' == "a\\b"`, true},
+	}
+
+	for _, tc := range table {
+		fn, err := parseExpr(tc.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.expr, err)
+		}
+
+		got, err := fn(values)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.expr, err)
+		}
+
+		if truthy(got) != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+
+	for _, el := range []string{`cloud ==`, `(cloud`, `cloud "aws"`, `"aws`, `a = b`, `a && || b`, `€ == 1`, `'\q'`} {
+		if _, err := parseExpr(el); err == nil {
+			t.Errorf("%s: expected error", el)
+		}
+	}
+
+	fn, err := parseExpr(`cloud > 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fn(values); err == nil {
+		t.Errorf("expected error comparing a string with a number")
+	}
+}
+
+func TestCopyDirRules(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader(`
+# optional pieces
+Dockerfile     containerized
+terraform/     cloud != "none"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = rules.Evaluate(map[string]interface{}{"containerized": true, "cloud": "none"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var excluded []string
+	res := copyFixture(t, map[string]string{
+		"skel/Dockerfile":        "content",
+		"skel/terraform/main.tf": "content",
+		"skel/README.md":         "content",
+	}, withCopyOpts(func(co *CopyOpts) {
+		co.Rules = rules
+		co.RuleExcluded = func(path string, rule *Rule) {
+			excluded = append(excluded, path+" "+rule.Glob)
+		}
+	}))
+
+	if len(excluded) != 1 || excluded[0] != "skel/terraform terraform/" {
+		t.Fatalf("unexpected excluded paths: %v", excluded)
+	}
+
+	for _, el := range []string{"Dockerfile", "README.md"} {
+		if _, err := res.FS().Stat(el); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := res.FS().Stat("terraform"); err == nil {
+		t.Fatalf("expected terraform not to be copied")
+	}
+}
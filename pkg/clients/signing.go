@@ -0,0 +1,48 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/krateoplatformops/provider-git/apis/v1alpha1"
+	"github.com/krateoplatformops/provider-git/pkg/clients/git"
+	"github.com/krateoplatformops/provider-git/pkg/helpers"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	signingFormatOpenPGP = "openpgp"
+	signingFormatSSH     = "ssh"
+)
+
+// getSigner returns the signer for the commits and tags, if configured.
+func getSigner(ctx context.Context, k client.Client, pc *v1alpha1.ProviderConfig) (git.Signer, error) {
+	cfg := pc.Spec.Signing
+	if cfg == nil {
+		return nil, nil
+	}
+
+	key, err := helpers.GetSecret(ctx, k, cfg.KeySecretRef.DeepCopy())
+	if err != nil {
+		return nil, err
+	}
+
+	var passphrase string
+	if cfg.PassphraseSecretRef != nil {
+		passphrase, err = helpers.GetSecret(ctx, k, cfg.PassphraseSecretRef.DeepCopy())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	format := helpers.StringValue(cfg.Format)
+	switch {
+	case len(format) == 0, strings.EqualFold(format, signingFormatOpenPGP):
+		return git.NewOpenPGPSigner([]byte(key), []byte(passphrase))
+	case strings.EqualFold(format, signingFormatSSH):
+		return git.NewSSHSigner([]byte(key), []byte(passphrase))
+	default:
+		return nil, fmt.Errorf("unsupported signing format: %s", format)
+	}
+}
@@ -0,0 +1,78 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/krateoplatformops/provider-git/apis/v1alpha1"
+	"github.com/krateoplatformops/provider-git/pkg/helpers"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// sshUser is the user of the ssh URLs without one.
+const sshUser = "git"
+
+// getSSHAuthMethod returns a public key auth method for the supplied private key,
+// authenticating as the repository URL user (default: git).
+// The server host key is verified against the configured known_hosts entries.
+func getSSHAuthMethod(ctx context.Context, k client.Client, creds *v1alpha1.RepoCredentials, privateKey string) (transport.AuthMethod, error) {
+	hostKeyCallback, err := knownHostsCallback(helpers.StringValue(creds.KnownHosts))
+	if err != nil {
+		return nil, err
+	}
+
+	var passphrase string
+	if creds.PassphraseSecretRef != nil {
+		passphrase, err = helpers.GetSecret(ctx, k, creds.PassphraseSecretRef.DeepCopy())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := gitssh.NewPublicKeys(sshUser, []byte(privateKey), passphrase)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse ssh private key")
+	}
+	res.HostKeyCallback = hostKeyCallback
+
+	return res, nil
+}
+
+// knownHostsCallback returns an host key callback that accepts only the keys
+// listed in the supplied known_hosts entries, with the OpenSSH semantics
+// (hashed hostnames, negations, wildcards, '@revoked' and '@cert-authority').
+func knownHostsCallback(data string) (ssh.HostKeyCallback, error) {
+	if len(strings.TrimSpace(data)) == 0 {
+		return nil, fmt.Errorf("known hosts must be specified with ssh auth method")
+	}
+
+	// the known_hosts database can be loaded only from files
+	f, err := ioutil.TempFile("", "known_hosts")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := knownhosts.New(f.Name())
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse known hosts")
+	}
+
+	return res, nil
+}
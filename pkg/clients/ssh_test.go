@@ -0,0 +1,98 @@
+package clients
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func newHostKey(t *testing.T) (ssh.PublicKey, ssh.Signer) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key, signer
+}
+
+func TestKnownHostsCallback(t *testing.T) {
+	key, _ := newHostKey(t)
+	other, _ := newHostKey(t)
+	caKey, ca := newHostKey(t)
+
+	cert := &ssh.Certificate{
+		Key:             key,
+		CertType:        ssh.HostCert,
+		ValidPrincipals: []string{"ca.example.com"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+
+	line := func(hosts string, k ssh.PublicKey) string {
+		return hosts + " " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(k)))
+	}
+
+	table := []struct {
+		name       string
+		knownHosts string
+		hostname   string
+		key        ssh.PublicKey
+		ok         bool
+	}{
+		{"plain", line("github.com", key), "github.com:22", key, true},
+		{"plain other key", line("github.com", other), "github.com:22", key, false},
+		{"plain other host", line("gitlab.com", key), "github.com:22", key, false},
+		{"port", line("[git.example.com]:2222", key), "git.example.com:2222", key, true},
+		{"port mismatch", line("[git.example.com]:2222", key), "git.example.com:22", key, false},
+		{"port missing", line("git.example.com", key), "git.example.com:2222", key, false},
+		{"hashed", line(knownhosts.HashHostname("github.com"), key), "github.com:22", key, true},
+		{"hashed other host", line(knownhosts.HashHostname("gitlab.com"), key), "github.com:22", key, false},
+		{"wildcard", line("*.example.com", key), "git.example.com:22", key, true},
+		{"wildcard other domain", line("*.example.com", key), "git.example.org:22", key, false},
+		{"single char wildcard", line("git?.example.com", key), "git1.example.com:22", key, true},
+		{"negation", line("*.example.com,!evil.example.com", key), "evil.example.com:22", key, false},
+		{"negation other host", line("*.example.com,!evil.example.com", key), "git.example.com:22", key, true},
+		{"revoked", line("github.com", key) + "\n@revoked " + line("*", key), "github.com:22", key, false},
+		{"cert authority", "@cert-authority " + line("*.example.com", caKey), "ca.example.com:22", cert, true},
+		{"cert authority other domain", "@cert-authority " + line("*.example.com", caKey), "ca.example.org:22", cert, false},
+		{"cert authority as host key", line("ca.example.com", caKey), "ca.example.com:22", cert, false},
+	}
+
+	remote := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 22}
+	for _, tc := range table {
+		cb, err := knownHostsCallback(tc.knownHosts)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		err = cb(tc.hostname, remote, tc.key)
+		if tc.ok != (err == nil) {
+			t.Errorf("%s: unexpected result (err: %v)", tc.name, err)
+		}
+	}
+}
+
+func TestKnownHostsCallbackInvalid(t *testing.T) {
+	for _, el := range []string{"", "  \n", "github.com not-a-key"} {
+		if _, err := knownHostsCallback(el); err == nil {
+			t.Errorf("%q: expected error", el)
+		}
+	}
+}
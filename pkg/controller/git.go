@@ -4,18 +4,17 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/krateoplatformops/provider-git/pkg/clients/git"
 	"github.com/krateoplatformops/provider-git/pkg/controller/config"
 	"github.com/krateoplatformops/provider-git/pkg/controller/repo"
 )
 
-func Setup(mgr ctrl.Manager, o controller.Options) error {
-	for _, setup := range []func(ctrl.Manager, controller.Options) error{
-		config.Setup,
-		repo.Setup,
-	} {
-		if err := setup(mgr, o); err != nil {
-			return err
-		}
+// Setup creates all the controllers; the optional cache is shared
+// by all the git operations.
+func Setup(mgr ctrl.Manager, o controller.Options, cache *git.Cache) error {
+	if err := config.Setup(mgr, o); err != nil {
+		return err
 	}
-	return nil
+
+	return repo.Setup(mgr, o, cache)
 }
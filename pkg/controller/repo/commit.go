@@ -0,0 +1,83 @@
+package repo
+
+import (
+	"github.com/cbroglie/mustache"
+	gitv1alpha1 "github.com/krateoplatformops/provider-git/apis/v1alpha1"
+	"github.com/krateoplatformops/provider-git/pkg/clients/git"
+	"github.com/krateoplatformops/provider-git/pkg/helpers"
+)
+
+const defaultCommitMessage = ":rocket: first commit"
+
+// createCommitOpts merges the Repo commit settings over the ProviderConfig ones
+// and renders the message template with the file templates values.
+func createCommitOpts(pc, cr *gitv1alpha1.CommitOpts, values map[string]interface{}, deploymentId string) (*git.CommitOpts, error) {
+	merged := mergeCommitOpts(pc, cr)
+
+	res := &git.CommitOpts{
+		Message:   defaultCommitMessage,
+		Author:    toIdentity(merged.Author),
+		Committer: toIdentity(merged.Committer),
+	}
+
+	tmpl := helpers.StringValue(merged.MessageTemplate)
+	if len(tmpl) == 0 {
+		return res, nil
+	}
+
+	data := map[string]interface{}{}
+	for k, v := range values {
+		data[k] = v
+	}
+	if _, ok := data[labDeploymentId]; !ok {
+		data[labDeploymentId] = deploymentId
+	}
+
+	// a commit message is plain text: the values are not HTML escaped
+	msg, err := mustache.RenderRaw(tmpl, true, data)
+	if err != nil {
+		return nil, err
+	}
+	res.Message = msg
+
+	return res, nil
+}
+
+// tagger returns the identity tagging the scaffold: the committer or,
+// if missing, the author.
+func tagger(pc, cr *gitv1alpha1.CommitOpts) *git.Identity {
+	merged := mergeCommitOpts(pc, cr)
+	if merged.Committer != nil {
+		return toIdentity(merged.Committer)
+	}
+
+	return toIdentity(merged.Author)
+}
+
+func mergeCommitOpts(pc, cr *gitv1alpha1.CommitOpts) *gitv1alpha1.CommitOpts {
+	res := &gitv1alpha1.CommitOpts{}
+	for _, el := range []*gitv1alpha1.CommitOpts{pc, cr} {
+		if el == nil {
+			continue
+		}
+		if el.Author != nil {
+			res.Author = el.Author
+		}
+		if el.Committer != nil {
+			res.Committer = el.Committer
+		}
+		if el.MessageTemplate != nil {
+			res.MessageTemplate = el.MessageTemplate
+		}
+	}
+
+	return res
+}
+
+func toIdentity(id *gitv1alpha1.Identity) *git.Identity {
+	if id == nil {
+		return nil
+	}
+
+	return &git.Identity{Name: id.Name, Email: id.Email}
+}
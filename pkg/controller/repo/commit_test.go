@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"reflect"
+	"testing"
+
+	gitv1alpha1 "github.com/krateoplatformops/provider-git/apis/v1alpha1"
+	"github.com/krateoplatformops/provider-git/pkg/clients/git"
+	"github.com/krateoplatformops/provider-git/pkg/helpers"
+)
+
+func TestCreateCommitOpts(t *testing.T) {
+	bot := &gitv1alpha1.Identity{Name: "Krateo Bot", Email: "bot@krateo.io"}
+	ci := &gitv1alpha1.Identity{Name: "CI", Email: "ci@krateo.io"}
+	dev := &gitv1alpha1.Identity{Name: "Dev", Email: "dev@krateo.io"}
+
+	values := map[string]interface{}{"name": "R&D <tools>", "owner": `"team"`}
+
+	table := []struct {
+		name string
+		pc   *gitv1alpha1.CommitOpts
+		cr   *gitv1alpha1.CommitOpts
+		want *git.CommitOpts
+	}{
+		{
+			name: "defaults",
+			want: &git.CommitOpts{Message: defaultCommitMessage},
+		},
+		{
+			name: "provider config",
+			pc:   &gitv1alpha1.CommitOpts{Author: bot, Committer: ci, MessageTemplate: helpers.StringPtr("scaffold {{deploymentId}}")},
+			want: &git.CommitOpts{
+				Message:   "scaffold d3pl0y",
+				Author:    &git.Identity{Name: "Krateo Bot", Email: "bot@krateo.io"},
+				Committer: &git.Identity{Name: "CI", Email: "ci@krateo.io"},
+			},
+		},
+		{
+			name: "repo over provider config",
+			pc:   &gitv1alpha1.CommitOpts{Author: bot, Committer: ci, MessageTemplate: helpers.StringPtr("scaffold")},
+			cr:   &gitv1alpha1.CommitOpts{Author: dev},
+			want: &git.CommitOpts{
+				Message:   "scaffold",
+				Author:    &git.Identity{Name: "Dev", Email: "dev@krateo.io"},
+				Committer: &git.Identity{Name: "CI", Email: "ci@krateo.io"},
+			},
+		},
+		{
+			name: "values not escaped",
+			cr:   &gitv1alpha1.CommitOpts{MessageTemplate: helpers.StringPtr("feat: {{name}} by {{owner}}\n\nDeployment: {{{deploymentId}}}")},
+			want: &git.CommitOpts{Message: "feat: R&D <tools> by \"team\"\n\nDeployment: d3pl0y"},
+		},
+	}
+
+	for _, tc := range table {
+		got, err := createCommitOpts(tc.pc, tc.cr, values, testDeploymentId)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+
+	if _, err := createCommitOpts(nil, &gitv1alpha1.CommitOpts{MessageTemplate: helpers.StringPtr("{{#name}}")}, values, testDeploymentId); err == nil {
+		t.Fatalf("expected error rendering an invalid template")
+	}
+}
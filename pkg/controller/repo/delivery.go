@@ -0,0 +1,102 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+
+	repov1alpha1 "github.com/krateoplatformops/provider-git/apis/repo/v1alpha1"
+	"github.com/krateoplatformops/provider-git/pkg/clients/hosting"
+	"github.com/krateoplatformops/provider-git/pkg/helpers"
+)
+
+const (
+	deliveryPullRequest = "PullRequest"
+	availableWhenOpened = "Opened"
+	featureBranchPrefix = "krateo/"
+)
+
+// isPullRequestDelivery tells if the scaffold is delivered by a pull request.
+func isPullRequestDelivery(spec *repov1alpha1.ToRepoOpts) bool {
+	return spec.Delivery != nil &&
+		strings.EqualFold(helpers.StringValue(spec.Delivery.Mode), deliveryPullRequest)
+}
+
+// featureBranch returns the pull request source branch.
+func featureBranch(spec *repov1alpha1.ToRepoOpts, deploymentId string) string {
+	if name := helpers.StringValue(spec.Delivery.FeatureBranch); len(name) > 0 {
+		return name
+	}
+
+	return featureBranchPrefix + deploymentId
+}
+
+// toRepoCoordinates returns the owner and the name of the target repo
+// as known by the hosting service.
+func toRepoCoordinates(spec *repov1alpha1.ToRepoOpts) (owner, name string, err error) {
+	owner, name, err = hosting.RepoFromURL(spec.Url)
+	if err != nil {
+		return "", "", err
+	}
+
+	if spec.CreateIfMissing != nil {
+		if o := helpers.StringValue(spec.CreateIfMissing.Owner); len(o) > 0 {
+			owner = o
+		}
+	}
+
+	return owner, name, nil
+}
+
+// pullRequestOpts describes the pull request from the feature branch to the base one.
+func pullRequestOpts(spec *repov1alpha1.ToRepoOpts, base, deploymentId string) (*hosting.PullRequestOpts, error) {
+	owner, name, err := toRepoCoordinates(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hosting.PullRequestOpts{
+		Owner:       owner,
+		Name:        name,
+		Head:        featureBranch(spec, deploymentId),
+		Base:        base,
+		Title:       helpers.StringValue(spec.Delivery.Title),
+		Description: fmt.Sprintf("Scaffold of the deployment %s.", deploymentId),
+	}, nil
+}
+
+// findPullRequest returns the pull request delivering the scaffold to the base branch, if any.
+func (e *external) findPullRequest(ctx context.Context, spec *repov1alpha1.ToRepoOpts, base, deploymentId string) (*hosting.PullRequest, error) {
+	if e.cfg.Hosting == nil {
+		return nil, errors.New(errMissingToRepoApi)
+	}
+
+	opts, err := pullRequestOpts(spec, base, deploymentId)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.queryPullRequest(ctx, opts)
+}
+
+// setPullRequestStatus records the pull request and sets the Ready condition
+// according to the delivery policy.
+func setPullRequestStatus(cr *repov1alpha1.Repo, pr *hosting.PullRequest) {
+	cr.Status.AtProvider.PullRequestUrl = helpers.StringPtr(pr.Url)
+	cr.Status.AtProvider.PullRequestState = helpers.StringPtr(pr.State)
+
+	availableWhen := helpers.StringValue(cr.Spec.ForProvider.ToRepo.Delivery.AvailableWhen)
+
+	switch {
+	case pr.State == hosting.PullRequestMerged,
+		pr.State == hosting.PullRequestOpen && strings.EqualFold(availableWhen, availableWhenOpened):
+		cr.SetConditions(xpv1.Available())
+	case pr.State == hosting.PullRequestOpen:
+		cr.SetConditions(xpv1.Creating())
+	default:
+		cr.SetConditions(xpv1.Unavailable())
+	}
+}
@@ -0,0 +1,186 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/krateoplatformops/provider-git/pkg/clients/git"
+)
+
+// Synced condition (and event) reasons of the git failures.
+const (
+	ReasonRepositoryNotFound   xpv1.ConditionReason = "RepositoryNotFound"
+	ReasonReferenceNotFound    xpv1.ConditionReason = "ReferenceNotFound"
+	ReasonRepositoryEmpty      xpv1.ConditionReason = "RepositoryEmpty"
+	ReasonAuthenticationFailed xpv1.ConditionReason = "AuthenticationFailed"
+	ReasonAuthorizationFailed  xpv1.ConditionReason = "AuthorizationFailed"
+	ReasonTLSError             xpv1.ConditionReason = "TLSError"
+	ReasonNetworkTimeout       xpv1.ConditionReason = "NetworkTimeout"
+	ReasonNonFastForward       xpv1.ConditionReason = "NonFastForward"
+	ReasonProtectedBranch      xpv1.ConditionReason = "ProtectedBranch"
+	ReasonTooLarge             xpv1.ConditionReason = "TooLarge"
+)
+
+var failureReasons = []struct {
+	err    error
+	reason xpv1.ConditionReason
+}{
+	{git.ErrRepositoryNotFound, ReasonRepositoryNotFound},
+	{git.ErrReferenceNotFound, ReasonReferenceNotFound},
+	{git.ErrEmptyRemoteRepository, ReasonRepositoryEmpty},
+	{git.ErrAuthenticationRequired, ReasonAuthenticationFailed},
+	{git.ErrAuthorizationFailed, ReasonAuthorizationFailed},
+	{git.ErrLocalRepoNotAllowed, ReasonAuthorizationFailed},
+	{git.ErrTLS, ReasonTLSError},
+	{git.ErrTimeout, ReasonNetworkTimeout},
+	// the deployment and hosting service calls
+	{context.DeadlineExceeded, ReasonNetworkTimeout},
+	{os.ErrDeadlineExceeded, ReasonNetworkTimeout},
+	{git.ErrNonFastForward, ReasonNonFastForward},
+	{git.ErrReplayConflict, ReasonNonFastForward},
+	{git.ErrProtectedBranch, ReasonProtectedBranch},
+	{git.ErrTooLarge, ReasonTooLarge},
+}
+
+// failureReason returns the reason of a git failure, if known.
+func failureReason(err error) (xpv1.ConditionReason, bool) {
+	for _, el := range failureReasons {
+		if errors.Is(err, el.err) {
+			return el.reason, true
+		}
+	}
+
+	return "", false
+}
+
+// failure is the git failure of the last external client call.
+type failure struct {
+	reason  xpv1.ConditionReason
+	message string
+}
+
+// failureTracker hands the reason of the last external client failure over
+// to the event recorder and the status writer of the managed reconciler,
+// which know just the error message.
+type failureTracker struct {
+	mu       sync.Mutex
+	failures map[types.UID]failure
+}
+
+func newFailureTracker() *failureTracker {
+	return &failureTracker{
+		failures: map[types.UID]failure{},
+	}
+}
+
+// track records the reason of the error, if known, for the managed resource.
+func (t *failureTracker) track(mg resource.Managed, err error) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if reason, ok := failureReason(err); ok {
+		t.failures[mg.GetUID()] = failure{reason: reason, message: err.Error()}
+		return
+	}
+
+	delete(t.failures, mg.GetUID())
+}
+
+// get returns the tracked failure of the object, removing it if pop is set.
+func (t *failureTracker) get(o runtime.Object, pop bool) (failure, bool) {
+	mo, ok := o.(metav1.Object)
+	if !ok {
+		return failure{}, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	res, ok := t.failures[mo.GetUID()]
+	if pop {
+		delete(t.failures, mo.GetUID())
+	}
+
+	return res, ok
+}
+
+// reasonRecorder records the warning event of a git failure with its reason
+// in place of the generic one of the managed reconciler.
+type reasonRecorder struct {
+	event.Recorder
+
+	failures *failureTracker
+}
+
+func (r *reasonRecorder) Event(o runtime.Object, e event.Event) {
+	if f, ok := r.failures.get(o, false); ok && e.Type == event.TypeWarning && e.Message == f.message {
+		e.Reason = event.Reason(f.reason)
+	}
+
+	r.Recorder.Event(o, e)
+}
+
+func (r *reasonRecorder) WithAnnotations(keysAndValues ...string) event.Recorder {
+	return &reasonRecorder{
+		Recorder: r.Recorder.WithAnnotations(keysAndValues...),
+		failures: r.failures,
+	}
+}
+
+// reasonManager hands the managed reconciler a client whose status writer
+// sets the git failure reason on the Synced condition, in place of the
+// generic ReconcileError, within the status update of the reconcile.
+type reasonManager struct {
+	manager.Manager
+
+	failures *failureTracker
+}
+
+func (m *reasonManager) GetClient() client.Client {
+	return &reasonClient{Client: m.Manager.GetClient(), failures: m.failures}
+}
+
+type reasonClient struct {
+	client.Client
+
+	failures *failureTracker
+}
+
+func (c *reasonClient) Status() client.StatusWriter {
+	return &reasonStatusWriter{StatusWriter: c.Client.Status(), failures: c.failures}
+}
+
+type reasonStatusWriter struct {
+	client.StatusWriter
+
+	failures *failureTracker
+}
+
+func (w *reasonStatusWriter) Update(ctx context.Context, o client.Object, opts ...client.UpdateOption) error {
+	f, ok := w.failures.get(o, true)
+	if mg, isManaged := o.(resource.Managed); ok && isManaged {
+		cond := mg.GetCondition(xpv1.TypeSynced)
+		if cond.Status == corev1.ConditionFalse && cond.Reason == xpv1.ReasonReconcileError {
+			cond.Reason = f.reason
+			mg.SetConditions(cond)
+		}
+	}
+
+	return w.StatusWriter.Update(ctx, o, opts...)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
 	"strings"
 
 	"github.com/cbroglie/mustache"
@@ -25,13 +26,14 @@ import (
 
 	repov1alpha1 "github.com/krateoplatformops/provider-git/apis/repo/v1alpha1"
 	"github.com/krateoplatformops/provider-git/pkg/clients"
-	"github.com/krateoplatformops/provider-git/pkg/clients/deployment"
 	"github.com/krateoplatformops/provider-git/pkg/clients/git"
+	"github.com/krateoplatformops/provider-git/pkg/clients/hosting"
 	"github.com/krateoplatformops/provider-git/pkg/clients/repo"
 	"github.com/krateoplatformops/provider-git/pkg/helpers"
 
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
 
+	"github.com/go-git/go-git/v5/plumbing/format/gitattributes"
 	gi "github.com/sabhiram/go-gitignore"
 
 	corev1 "k8s.io/api/core/v1"
@@ -40,29 +42,45 @@ import (
 const (
 	labDeploymentId = "deploymentId"
 
+	// annFromRepoCommitId: the origin repo commit SHA used to scaffold the target
+	// repo; unlike the status it survives the update following the creation.
+	annFromRepoCommitId = "git.krateo.io/from-repo-commit-id"
+
+	pushPolicyForce = "Force"
+	defaultBranch   = "main"
+	maxPushRetries  = 3
+
 	errNotRepo                         = "managed resource is not a repo custom resource"
 	errMissingDeploymentIdLabel        = "managed resource is missing 'deploymentId' label"
 	errUnableToLoadConfigMapWithValues = "unable to load configmap with template values"
 	errConfigMapValuesNotReadyYet      = "configmap values not ready yet"
+	errMissingToRepoApi                = "providerconfig 'toRepoApi' must be specified"
 )
 
 // Setup adds a controller that reconciles Token managed resources.
-func Setup(mgr ctrl.Manager, o controller.Options) error {
+func Setup(mgr ctrl.Manager, o controller.Options, cache *git.Cache) error {
 	name := managed.ControllerName(repov1alpha1.RepoGroupKind)
 
 	log := o.Logger.WithValues("controller", name)
 
 	recorder := mgr.GetEventRecorderFor(name)
 
-	r := managed.NewReconciler(mgr,
+	failures := newFailureTracker()
+
+	r := managed.NewReconciler(&reasonManager{Manager: mgr, failures: failures},
 		resource.ManagedKind(repov1alpha1.RepoGroupVersionKind),
 		managed.WithExternalConnecter(&connector{
 			kube:     mgr.GetClient(),
 			log:      log,
 			recorder: recorder,
+			cache:    cache,
+			failures: failures,
 		}),
 		managed.WithLogger(log),
-		managed.WithRecorder(event.NewAPIRecorder(recorder)))
+		managed.WithRecorder(&reasonRecorder{
+			Recorder: event.NewAPIRecorder(recorder),
+			failures: failures,
+		}))
 
 	return ctrl.NewControllerManagedBy(mgr).
 		Named(name).
@@ -75,6 +93,8 @@ type connector struct {
 	kube     client.Client
 	log      logging.Logger
 	recorder record.EventRecorder
+	cache    *git.Cache
+	failures *failureTracker
 }
 
 func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
@@ -89,23 +109,29 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 	}
 
 	return &external{
-		kube: c.kube,
-		log:  c.log,
-		cfg:  cfg,
-		rec:  c.recorder,
+		kube:     c.kube,
+		log:      c.log,
+		cfg:      cfg,
+		rec:      c.recorder,
+		cache:    c.cache,
+		failures: c.failures,
 	}, nil
 }
 
 // An ExternalClient observes, then either creates, updates, or deletes an
 // external resource to ensure it reflects the managed resource's desired state.
 type external struct {
-	kube client.Client
-	log  logging.Logger
-	cfg  *clients.Config
-	rec  record.EventRecorder
+	kube     client.Client
+	log      logging.Logger
+	cfg      *clients.Config
+	rec      record.EventRecorder
+	cache    *git.Cache
+	failures *failureTracker
 }
 
-func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+func (e *external) Observe(ctx context.Context, mg resource.Managed) (obs managed.ExternalObservation, err error) {
+	defer func() { e.failures.track(mg, err) }()
+
 	cr, ok := mg.(*repov1alpha1.Repo)
 	if !ok {
 		return managed.ExternalObservation{}, errors.New(errNotRepo)
@@ -141,38 +167,116 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		}
 	}
 
-	toRepo, err := git.Clone(spec.ToRepo.Url, e.cfg.ToRepoCreds, e.cfg.Insecure)
-	if err != nil {
-		return managed.ExternalObservation{}, err
+	toOpts := &git.CloneOpts{
+		URL:        spec.ToRepo.Url,
+		Auth:       e.cfg.ToRepoCreds,
+		Insecure:   e.cfg.Insecure,
+		HTTPClient: e.cfg.HTTPClient,
+		Cache:      e.cache,
 	}
-	e.log.Debug("Target repo cloned", "url", spec.ToRepo.Url)
 
-	clmOk, err := toRepo.Exists("deployment.yaml")
+	branch, commitId, err := e.branchTip(ctx, toOpts, helpers.StringValue(spec.ToRepo.Branch))
+	if errors.Is(err, git.ErrRepositoryNotFound) && spec.ToRepo.CreateIfMissing != nil {
+		e.log.Debug("Target repo not found", "url", spec.ToRepo.Url)
+
+		return managed.ExternalObservation{
+			ResourceExists:   false,
+			ResourceUpToDate: true,
+		}, nil
+	}
 	if err != nil {
 		return managed.ExternalObservation{}, err
 	}
 
-	if clmOk {
-		e.log.Debug("Claim found", "url", spec.ToRepo.Url)
-
-		cr.Status.AtProvider.DeploymentId = helpers.StringPtr(getDeploymentId(mg))
-		cr.SetConditions(xpv1.Available())
+	if len(commitId) == 0 {
+		e.log.Debug("Target branch not found", "url", spec.ToRepo.Url, "branch", branch)
 
 		return managed.ExternalObservation{
-			ResourceExists:   true,
+			ResourceExists:   false,
 			ResourceUpToDate: true,
 		}, nil
 	}
 
-	e.log.Debug("Target repo is empty", "url", spec.ToRepo.Url)
+	if isPullRequestDelivery(&spec.ToRepo) {
+		pr, err := e.findPullRequest(ctx, &spec.ToRepo, branch, deploymentID)
+		if err != nil {
+			return managed.ExternalObservation{}, err
+		}
+
+		if pr != nil {
+			e.log.Debug("Pull request found", "url", pr.Url, "state", pr.State)
+
+			cr.Status.AtProvider.DeploymentId = helpers.StringPtr(deploymentID)
+			cr.Status.AtProvider.Branch = helpers.StringPtr(branch)
+			setFromRepoCommitId(cr)
+			setPullRequestStatus(cr, pr)
+
+			// rejected scaffold: the next Create pushes it again and opens a new pull request
+			if pr.State == hosting.PullRequestClosed {
+				e.log.Debug("Pull request closed without merging", "url", pr.Url)
+				e.rec.Eventf(cr, corev1.EventTypeWarning, "PullRequestClosed", "Pull request %s closed without merging, opening a new one", pr.Url)
+
+				return managed.ExternalObservation{
+					ResourceExists:   false,
+					ResourceUpToDate: true,
+				}, nil
+			}
+
+			// the tag waits for the scaffold to reach the base branch
+			tagMissing := false
+			if pr.State == hosting.PullRequestMerged {
+				tagMissing, err = e.tagMissing(ctx, toOpts, spec.Tag)
+				if err != nil {
+					return managed.ExternalObservation{}, err
+				}
+			}
+
+			return managed.ExternalObservation{
+				ResourceExists:   true,
+				ResourceUpToDate: !tagMissing,
+			}, nil
+		}
+	}
+
+	// the branch moved since the last time we checked: look for the claim again
+	if commitId != helpers.StringValue(cr.Status.AtProvider.CommitId) {
+		clmOk, err := e.hasFile(ctx, toOpts, branch, "deployment.yaml")
+		if err != nil {
+			return managed.ExternalObservation{}, err
+		}
+
+		if !clmOk {
+			e.log.Debug("Claim not found", "url", spec.ToRepo.Url, "branch", branch, "commitId", commitId)
+
+			return managed.ExternalObservation{
+				ResourceExists:   false,
+				ResourceUpToDate: true,
+			}, nil
+		}
+	}
+
+	e.log.Debug("Claim found", "url", spec.ToRepo.Url, "branch", branch, "commitId", commitId)
+
+	cr.Status.AtProvider.DeploymentId = helpers.StringPtr(getDeploymentId(mg))
+	cr.Status.AtProvider.Branch = helpers.StringPtr(branch)
+	cr.Status.AtProvider.CommitId = helpers.StringPtr(commitId)
+	setFromRepoCommitId(cr)
+	cr.SetConditions(xpv1.Available())
+
+	tagMissing, err := e.tagMissing(ctx, toOpts, spec.Tag)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
 
 	return managed.ExternalObservation{
-		ResourceExists:   false,
-		ResourceUpToDate: true,
+		ResourceExists:   true,
+		ResourceUpToDate: !tagMissing,
 	}, nil
 }
 
-func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+func (e *external) Create(ctx context.Context, mg resource.Managed) (cre managed.ExternalCreation, err error) {
+	defer func() { e.failures.track(mg, err) }()
+
 	cr, ok := mg.(*repov1alpha1.Repo)
 	if !ok {
 		return managed.ExternalCreation{}, errors.New(errNotRepo)
@@ -184,7 +288,12 @@ func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 
 	deploymentId := getDeploymentId(mg)
 
-	claim, err := deployment.Get(e.cfg.DeploymentServiceUrl, deploymentId)
+	pullRequest := isPullRequestDelivery(&spec.ToRepo)
+	if pullRequest && e.cfg.Hosting == nil {
+		return managed.ExternalCreation{}, errors.New(errMissingToRepoApi)
+	}
+
+	claim, err := e.getDeployment(ctx, deploymentId)
 	if err != nil {
 		return managed.ExternalCreation{},
 			fmt.Errorf("fetching deployment (deploymentId: %s): %w", deploymentId, err)
@@ -193,35 +302,102 @@ func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 	e.log.Debug("Claim fetched", "deploymentId", deploymentId)
 	e.rec.Eventf(cr, corev1.EventTypeNormal, "ClaimFetched", "Successfully fetched claim for deployment: %s", deploymentId)
 
-	toRepo, err := git.Clone(spec.ToRepo.Url, e.cfg.ToRepoCreds, e.cfg.Insecure)
+	branch := helpers.StringValue(spec.ToRepo.Branch)
+
+	toOpts := &git.CloneOpts{
+		URL:        spec.ToRepo.Url,
+		Auth:       e.cfg.ToRepoCreds,
+		Insecure:   e.cfg.Insecure,
+		HTTPClient: e.cfg.HTTPClient,
+		Signer:     e.cfg.Signer,
+		Ref:        branchRef(branch),
+		Shallow:    true,
+		Cache:      e.cache,
+	}
+	toRepo, err := e.clone(ctx, toOpts)
+	if errors.Is(err, git.ErrRepositoryNotFound) && spec.ToRepo.CreateIfMissing != nil {
+		if err := e.createToRepo(ctx, cr); err != nil {
+			return managed.ExternalCreation{}, err
+		}
+		toRepo, err = e.clone(ctx, toOpts)
+	}
+	if errors.Is(err, git.ErrReferenceNotFound) {
+		// new branch: it will be forked from the remote HEAD
+		toOpts.Ref = ""
+		toRepo, err = e.clone(ctx, toOpts)
+	}
+	switch {
+	case errors.Is(err, git.ErrEmptyRemoteRepository):
+		// no commits yet: the first push creates the branch
+		if len(branch) == 0 {
+			branch = defaultBranch
+		}
+		toRepo, err = git.Init(toOpts, branch)
+		if err != nil {
+			return managed.ExternalCreation{}, err
+		}
+		// nothing to merge into: the scaffold is pushed to the branch
+		pullRequest = false
+		e.log.Debug("Target repo initialized", "url", spec.ToRepo.Url, "branch", branch)
+		e.rec.Eventf(cr, corev1.EventTypeNormal, "TargetRepoInitialized", "Target repo %s is empty, initialized branch %s", spec.ToRepo.Url, branch)
+	case err != nil:
+		return managed.ExternalCreation{}, err
+	default:
+		e.log.Debug("Target repo cloned", "url", spec.ToRepo.Url)
+		e.rec.Eventf(cr, corev1.EventTypeNormal, "TargetRepoCloned", "Successfully cloned target repo: %s", spec.ToRepo.Url)
+	}
+
+	fromRepo, err := e.clone(ctx, &git.CloneOpts{
+		URL:               spec.FromRepo.Url,
+		Auth:              e.cfg.FromRepoCreds,
+		Insecure:          e.cfg.Insecure,
+		HTTPClient:        e.cfg.HTTPClient,
+		Ref:               helpers.StringValue(spec.FromRepo.Ref),
+		Shallow:           true,
+		Cache:             e.cache,
+		RecurseSubmodules: helpers.BoolValue(spec.FromRepo.RecurseSubmodules),
+	})
 	if err != nil {
 		return managed.ExternalCreation{}, err
 	}
-	e.log.Debug("Target repo cloned", "url", spec.ToRepo.Url)
-	e.rec.Eventf(cr, corev1.EventTypeNormal, "TargetRepoCloned", "Successfully cloned target repo: %s", spec.ToRepo.Url)
 
-	fromRepo, err := git.Clone(spec.FromRepo.Url, e.cfg.FromRepoCreds, e.cfg.Insecure)
+	fromCommitId, err := fromRepo.HeadCommitId()
 	if err != nil {
 		return managed.ExternalCreation{}, err
 	}
-	e.log.Debug("Origin repo cloned", "url", spec.FromRepo.Url)
-	e.rec.Eventf(cr, corev1.EventTypeNormal, "OriginRepoCloned", "Successfully cloned origin repo: %s", spec.FromRepo.Url)
+	e.log.Debug("Origin repo cloned", "url", spec.FromRepo.Url, "commitId", fromCommitId)
+	e.rec.Eventf(cr, corev1.EventTypeNormal, "OriginRepoCloned", "Successfully cloned origin repo: %s (commit: %s)", spec.FromRepo.Url, fromCommitId)
+
+	if len(branch) == 0 {
+		branch, err = toRepo.CurrentBranch()
+		if err != nil {
+			return managed.ExternalCreation{}, err
+		}
+	}
 
-	err = toRepo.Branch("main")
+	pushBranch := branch
+	if pullRequest {
+		pushBranch = featureBranch(&spec.ToRepo, deploymentId)
+	}
+
+	err = toRepo.Branch(pushBranch)
 	if err != nil {
 		return managed.ExternalCreation{}, err
 	}
-	e.log.Debug("Target repo on branch main")
+	e.log.Debug("Target repo on branch", "branch", pushBranch)
 
 	co := &repo.CopyOpts{
 		FromRepo: fromRepo,
 		ToRepo:   toRepo,
+		SymlinkRejected: func(path, target string) {
+			e.log.Info("Symlink rejected", "path", path, "target", target)
+			e.rec.Eventf(cr, corev1.EventTypeWarning, "SymlinkRejected", "Symlink %s -> %s resolves outside the copied tree: not copied", path, target)
+		},
 	}
 
-	// If fromPath is not specified DON'T COPY!
-	fromPath := helpers.StringValue(spec.FromRepo.Path)
-	if len(fromPath) > 0 {
-		values, err := e.loadValuesFromConfigMap(ctx, spec.ConfigMapKeyRef)
+	var values map[string]interface{}
+	if spec.ConfigMapKeyRef != nil {
+		values, err = e.loadValuesFromConfigMap(ctx, spec.ConfigMapKeyRef)
 		if err != nil {
 			e.log.Debug("Unable to load configmap with template data", "msg", err.Error())
 			e.rec.Eventf(cr, corev1.EventTypeWarning, "CannotLoadConfigMap", "Unable to load configmap with template data: %s", err.Error())
@@ -233,14 +409,37 @@ func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 			"namespace", spec.ConfigMapKeyRef.Namespace,
 			"values", values,
 		)
+	}
 
+	// If fromPath is not specified DON'T COPY!
+	fromPath := helpers.StringValue(spec.FromRepo.Path)
+	if len(fromPath) > 0 {
 		if err := loadIgnoreFileEventually(co); err != nil {
 			e.log.Info("Unable to load '.krateoignore'", "msg", err.Error())
 			e.rec.Eventf(cr, corev1.EventTypeWarning, "CannotLoadIgnoreFile", "Unable to load '.krateoignore' file: %s", err.Error())
 		}
 
+		if err := loadExcludeFileEventually(co); err != nil {
+			return managed.ExternalCreation{}, fmt.Errorf("loading '.krateoexclude': %w", err)
+		}
+
+		if err := loadAttributesEventually(co); err != nil {
+			e.log.Info("Unable to load '.gitattributes'", "msg", err.Error())
+			e.rec.Eventf(cr, corev1.EventTypeWarning, "CannotLoadAttributes", "Unable to load '.gitattributes' files: %s", err.Error())
+		}
+
 		createRenderFunc(co, values)
 
+		if err := loadRulesFileEventually(co, values); err != nil {
+			return managed.ExternalCreation{}, err
+		}
+
+		var excluded []string
+		co.RuleExcluded = func(path string, rule *repo.Rule) {
+			e.log.Debug("Path excluded by rule", "path", path, "glob", rule.Glob, "condition", rule.Condition)
+			excluded = append(excluded, path)
+		}
+
 		toPath := helpers.StringValue(spec.ToRepo.Path)
 		if len(toPath) == 0 {
 			toPath = "/"
@@ -250,6 +449,10 @@ func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 		if err != nil {
 			return managed.ExternalCreation{}, err
 		}
+
+		if len(co.Rules) > 0 {
+			e.rec.Eventf(cr, corev1.EventTypeNormal, "RulesEvaluated", "Template rules evaluated: %s", rulesDecisions(co.Rules, excluded))
+		}
 	}
 
 	// write claim data
@@ -266,28 +469,118 @@ func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 		"toPath", helpers.StringValue(spec.ToRepo.Path))
 	e.rec.Eventf(cr, corev1.EventTypeNormal, "RepoSyncSuccess", "Origin and target repo synchronized")
 
-	commitId, err := toRepo.Commit(".", ":rocket: first commit")
+	commitOpts, err := createCommitOpts(e.cfg.Commit, spec.Commit, values, deploymentId)
+	if err != nil {
+		return managed.ExternalCreation{}, fmt.Errorf("rendering commit message: %w", err)
+	}
+
+	commitId, err := toRepo.Commit(".", commitOpts)
+	if err != nil {
+		return managed.ExternalCreation{}, err
+	}
+	e.log.Debug("Target repo committed", "branch", pushBranch, "commitId", commitId)
+	e.rec.Eventf(cr, corev1.EventTypeNormal, "RepoCommitSuccess", "Target repo committed branch %s", pushBranch)
+
+	err = e.push(ctx, toRepo, &git.PushOpts{
+		RemoteName: "origin",
+		Branch:     pushBranch,
+		Insecure:   e.cfg.Insecure,
+		// the feature branch belongs to the Repo
+		Force:   pullRequest || strings.EqualFold(helpers.StringValue(spec.ToRepo.PushPolicy), pushPolicyForce),
+		Retries: maxPushRetries,
+	})
 	if err != nil {
 		return managed.ExternalCreation{}, err
 	}
-	e.log.Debug("Target repo committed branch main", "commitId", commitId)
-	e.rec.Eventf(cr, corev1.EventTypeNormal, "RepoCommitSuccess", "Target repo committed branch main")
 
-	err = toRepo.Push("origin", "main", e.cfg.Insecure)
+	// the commit may have been replayed on a newer branch tip
+	commitId, err = toRepo.HeadCommitId()
 	if err != nil {
 		return managed.ExternalCreation{}, err
 	}
-	e.log.Debug("Target repo pushed branch main", "deploymentId", deploymentId)
-	e.rec.Eventf(cr, corev1.EventTypeNormal, "RepoPushSuccess", "Target repo pushed branch main")
+	e.log.Debug("Target repo pushed", "branch", pushBranch, "deploymentId", deploymentId)
+	e.rec.Eventf(cr, corev1.EventTypeNormal, "RepoPushSuccess", "Target repo pushed branch %s", pushBranch)
+
+	switch {
+	case spec.Tag == nil:
+	case pullRequest:
+		// the feature branch commit may never reach the base branch
+		e.log.Debug("Target repo tag deferred", "tag", spec.Tag.Name, "branch", branch)
+		e.rec.Eventf(cr, corev1.EventTypeNormal, "TagDeferred", "Target repo tag %s will be created on branch %s once the pull request is merged", spec.Tag.Name, branch)
+	default:
+		err = e.pushTag(ctx, cr, toOpts, toRepo, spec.Tag, tagger(e.cfg.Commit, spec.Commit))
+		if err != nil {
+			return managed.ExternalCreation{}, err
+		}
+	}
 
-	cr.Status.SetConditions(xpv1.Available())
 	cr.Status.AtProvider.DeploymentId = helpers.StringPtr(deploymentId)
+	cr.Status.AtProvider.Branch = helpers.StringPtr(branch)
+	cr.Status.AtProvider.FromRepoCommitId = helpers.StringPtr(fromCommitId)
+	meta.AddAnnotations(cr, map[string]string{annFromRepoCommitId: fromCommitId})
+
+	if !pullRequest {
+		cr.Status.SetConditions(xpv1.Available())
+		cr.Status.AtProvider.CommitId = helpers.StringPtr(commitId)
+
+		return managed.ExternalCreation{}, nil
+	}
+
+	prOpts, err := pullRequestOpts(&spec.ToRepo, branch, deploymentId)
+	if err != nil {
+		return managed.ExternalCreation{}, err
+	}
+	if len(prOpts.Title) == 0 {
+		prOpts.Title = strings.SplitN(commitOpts.Message, "\n", 2)[0]
+	}
+
+	pr, err := e.openPullRequest(ctx, prOpts)
+	if err != nil {
+		return managed.ExternalCreation{}, fmt.Errorf("opening pull request from %s to %s: %w", pushBranch, branch, err)
+	}
+	e.log.Debug("Pull request opened", "url", pr.Url, "head", pushBranch, "base", branch)
+	e.rec.Eventf(cr, corev1.EventTypeNormal, "PullRequestOpened", "Opened pull request %s to branch %s", pr.Url, branch)
+
+	setPullRequestStatus(cr, pr)
 
 	return managed.ExternalCreation{}, nil
 }
 
-func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
-	return managed.ExternalUpdate{}, nil // noop
+// Update pushes the release tag missing from the target repo on the branch tip.
+func (e *external) Update(ctx context.Context, mg resource.Managed) (upd managed.ExternalUpdate, err error) {
+	defer func() { e.failures.track(mg, err) }()
+
+	cr, ok := mg.(*repov1alpha1.Repo)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotRepo)
+	}
+
+	spec := cr.Spec.ForProvider.DeepCopy()
+	if spec.Tag == nil {
+		return managed.ExternalUpdate{}, nil
+	}
+
+	toOpts := &git.CloneOpts{
+		URL:        spec.ToRepo.Url,
+		Auth:       e.cfg.ToRepoCreds,
+		Insecure:   e.cfg.Insecure,
+		HTTPClient: e.cfg.HTTPClient,
+		Signer:     e.cfg.Signer,
+		Ref:        branchRef(helpers.StringValue(cr.Status.AtProvider.Branch)),
+		Shallow:    true,
+		Cache:      e.cache,
+	}
+	toRepo, err := e.clone(ctx, toOpts)
+	if err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+
+	err = e.pushTag(ctx, cr, toOpts, toRepo, spec.Tag, tagger(e.cfg.Commit, spec.Commit))
+	if err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+
+	return managed.ExternalUpdate{}, nil
 }
 
 func (e *external) Delete(ctx context.Context, mg resource.Managed) error {
@@ -335,11 +628,20 @@ func createRenderFunc(cfg *repo.CopyOpts, values interface{}) {
 
 		return tmpl.FRender(out, values)
 	}
+
+	// the names are plain text: the values are not HTML escaped
+	cfg.RenderNameFunc = func(name string) (string, error) {
+		return mustache.RenderRaw(name, true, values)
+	}
 }
 
+// loadIgnoreFileEventually loads the template '.krateoignore' file, if any.
 func loadIgnoreFileEventually(cfg *repo.CopyOpts) error {
-	fp, err := cfg.FromRepo.FS().Open(".krateoignore")
+	fp, err := cfg.FromRepo.FS().Open(repo.IgnoreFile)
 	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
 		return err
 	}
 	defer fp.Close()
@@ -356,6 +658,189 @@ func loadIgnoreFileEventually(cfg *repo.CopyOpts) error {
 	return nil
 }
 
+// loadExcludeFileEventually loads the template '.krateoexclude' file, if any.
+func loadExcludeFileEventually(cfg *repo.CopyOpts) error {
+	fp, err := cfg.FromRepo.FS().Open(repo.ExcludeFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer fp.Close()
+
+	bs, err := ioutil.ReadAll(fp)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(string(bs), "\n")
+
+	cfg.Exclude = gi.CompileIgnoreLines(lines...)
+
+	return nil
+}
+
+// loadAttributesEventually loads the template '.gitattributes' files, if any.
+func loadAttributesEventually(cfg *repo.CopyOpts) error {
+	attrs, err := gitattributes.ReadPatterns(cfg.FromRepo.FS(), nil)
+	if err != nil {
+		return err
+	}
+
+	if len(attrs) > 0 {
+		cfg.Attributes = gitattributes.NewMatcher(attrs)
+	}
+
+	return nil
+}
+
+// loadRulesFileEventually parses and evaluates the template '.krateorules'
+// file, if any.
+func loadRulesFileEventually(cfg *repo.CopyOpts, values map[string]interface{}) error {
+	fp, err := cfg.FromRepo.FS().Open(repo.RulesFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer fp.Close()
+
+	rules, err := repo.ParseRules(fp)
+	if err != nil {
+		return fmt.Errorf("parsing '.krateorules': %w", err)
+	}
+
+	if err := rules.Evaluate(values); err != nil {
+		return fmt.Errorf("evaluating '.krateorules': %w", err)
+	}
+
+	cfg.Rules = rules
+
+	return nil
+}
+
+// rulesDecisions describes the rules outcome and the excluded paths.
+func rulesDecisions(rules repo.Rules, excluded []string) string {
+	res := make([]string, len(rules))
+	for i, el := range rules {
+		decision := "excluded"
+		if el.Include {
+			decision = "included"
+		}
+		res[i] = fmt.Sprintf("%s (%s): %s", el.Glob, el.Condition, decision)
+	}
+
+	msg := strings.Join(res, "; ")
+	if len(excluded) > 0 {
+		msg += "; excluded paths: " + strings.Join(excluded, ", ")
+	}
+
+	return msg
+}
+
+// branchRef returns the full reference name of the given branch, if any.
+func branchRef(name string) string {
+	if len(name) == 0 {
+		return ""
+	}
+
+	return "refs/heads/" + name
+}
+
+// createToRepo creates the target repository through the hosting service API.
+func (e *external) createToRepo(ctx context.Context, cr *repov1alpha1.Repo) error {
+	if e.cfg.Hosting == nil {
+		return errors.New(errMissingToRepoApi)
+	}
+
+	spec := cr.Spec.ForProvider.ToRepo
+
+	owner, name, err := toRepoCoordinates(&spec)
+	if err != nil {
+		return err
+	}
+
+	err = e.createRepo(ctx, &hosting.CreateRepoOpts{
+		Owner:       owner,
+		Name:        name,
+		Description: helpers.StringValue(spec.CreateIfMissing.Description),
+		Visibility:  helpers.StringValue(spec.CreateIfMissing.Visibility),
+	})
+	if err != nil {
+		return fmt.Errorf("creating target repo %s: %w", spec.Url, err)
+	}
+
+	e.log.Debug("Target repo created", "url", spec.Url, "owner", owner, "name", name)
+	e.rec.Eventf(cr, corev1.EventTypeNormal, "TargetRepoCreated", "Successfully created target repo: %s", spec.Url)
+
+	return nil
+}
+
+// tagMissing tells whether the release tag, if any, is missing from the target repo.
+func (e *external) tagMissing(ctx context.Context, toOpts *git.CloneOpts, tag *repov1alpha1.TagOpts) (bool, error) {
+	if tag == nil {
+		return false, nil
+	}
+
+	tags, err := e.tags(ctx, toOpts)
+	if err != nil {
+		return false, err
+	}
+
+	for _, el := range tags {
+		if el == tag.Name {
+			return false, nil
+		}
+	}
+
+	e.log.Debug("Target repo tag missing", "tag", tag.Name)
+
+	return true, nil
+}
+
+// pushTag creates the annotated tag on the pushed commit and pushes it,
+// unless the target repo already has it.
+func (e *external) pushTag(ctx context.Context, cr *repov1alpha1.Repo, toOpts *git.CloneOpts, toRepo *git.Repo, tag *repov1alpha1.TagOpts, tagger *git.Identity) error {
+	tags, err := e.tags(ctx, toOpts)
+	if err != nil {
+		return err
+	}
+
+	for _, el := range tags {
+		if el == tag.Name {
+			e.log.Debug("Target repo tag already exists", "tag", tag.Name)
+			e.rec.Eventf(cr, corev1.EventTypeNormal, "TagExists", "Target repo tag %s already exists", tag.Name)
+			return nil
+		}
+	}
+
+	_, err = toRepo.CreateTag(tag.Name, &git.TagOpts{
+		Message: helpers.StringValue(tag.Message),
+		Tagger:  tagger,
+	})
+	if err != nil {
+		return fmt.Errorf("creating tag %s: %w", tag.Name, err)
+	}
+
+	if err := e.pushTags(ctx, toRepo); err != nil {
+		return err
+	}
+
+	e.log.Debug("Target repo tag pushed", "tag", tag.Name)
+	e.rec.Eventf(cr, corev1.EventTypeNormal, "TagPushSuccess", "Target repo pushed tag %s", tag.Name)
+
+	return nil
+}
+
+// setFromRepoCommitId reports the origin repo commit SHA saved at creation.
+func setFromRepoCommitId(cr *repov1alpha1.Repo) {
+	if id, ok := cr.GetAnnotations()[annFromRepoCommitId]; ok {
+		cr.Status.AtProvider.FromRepoCommitId = helpers.StringPtr(id)
+	}
+}
+
 func getDeploymentId(mg resource.Managed) string {
 	for k, v := range mg.GetLabels() {
 		if k == labDeploymentId {
@@ -0,0 +1,946 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/krateoplatformops/provider-git/apis"
+	repov1alpha1 "github.com/krateoplatformops/provider-git/apis/repo/v1alpha1"
+	"github.com/krateoplatformops/provider-git/apis/v1alpha1"
+	"github.com/krateoplatformops/provider-git/pkg/clients"
+	"github.com/krateoplatformops/provider-git/pkg/clients/git"
+	"github.com/krateoplatformops/provider-git/pkg/clients/hosting"
+	"github.com/krateoplatformops/provider-git/pkg/clients/repo"
+	"github.com/krateoplatformops/provider-git/pkg/helpers"
+)
+
+const testDeploymentId = "d3pl0y"
+
+func TestMain(m *testing.M) {
+	// the test repositories are created in temporary directories
+	if err := git.AllowLocalRepos(os.TempDir()); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+// newRemote returns the path of a new bare repository with HEAD pointing to
+// the branch which, unless files is empty, holds a commit with the files.
+func newRemote(t *testing.T, branch string, files map[string]string) string {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "remote.git")
+	r, err := gogit.PlainInit(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head := plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(branch))
+	if err := r.Storer.SetReference(head); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) > 0 {
+		commitFiles(t, dir, branch, files)
+	}
+
+	return dir
+}
+
+// commitFiles pushes a commit with the files on top of the remote branch.
+func commitFiles(t *testing.T, dir, branch string, files map[string]string) string {
+	t.Helper()
+
+	ctx := context.Background()
+
+	opts := &git.CloneOpts{URL: dir, Ref: branch}
+	repo, err := git.Clone(ctx, opts)
+	if err != nil {
+		repo, err = git.Init(opts, branch)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		f, err := repo.FS().Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+		f.Close()
+	}
+
+	commitId, err := repo.Commit(".", &git.CommitOpts{Message: "update"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Push(ctx, &git.PushOpts{RemoteName: "origin", Branch: branch}); err != nil {
+		t.Fatal(err)
+	}
+
+	return commitId
+}
+
+// remoteCommit returns the tip commit of the remote branch, nil if missing.
+func remoteCommit(t *testing.T, dir, branch string) *object.Commit {
+	t.Helper()
+
+	r, err := gogit.PlainOpen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref, err := r.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return nil
+	}
+
+	commit, err := r.CommitObject(ref.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return commit
+}
+
+// remoteFile returns the content of the file in the tip commit of the
+// remote branch; false if the branch or the file is missing.
+func remoteFile(t *testing.T, dir, branch, path string) (string, bool) {
+	t.Helper()
+
+	commit := remoteCommit(t, dir, branch)
+	if commit == nil {
+		return "", false
+	}
+
+	f, err := commit.File(path)
+	if err != nil {
+		return "", false
+	}
+
+	res, err := f.Contents()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res, true
+}
+
+func newScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+
+	s := runtime.NewScheme()
+	if err := corev1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := apis.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+// newDeploymentService serves the claim of any deployment.
+func newDeploymentService(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"kind": "Deployment", "metadata": {"name": "%s"}}`, filepath.Base(r.URL.Path))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// newValues returns the configmap with the template values.
+func newValues(values string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "values", Namespace: "default"},
+		Data:       map[string]string{"values.json": values},
+	}
+}
+
+// newTestRepo returns a Repo copying the 'skel' folder of the template.
+func newTestRepo(fromUrl, toUrl string) *repov1alpha1.Repo {
+	cr := &repov1alpha1.Repo{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "demo",
+			Labels: map[string]string{labDeploymentId: testDeploymentId},
+		},
+	}
+	cr.Spec.ForProvider.FromRepo.Url = fromUrl
+	cr.Spec.ForProvider.FromRepo.Path = helpers.StringPtr("skel")
+	cr.Spec.ForProvider.ToRepo.Url = toUrl
+	cr.Spec.ForProvider.ConfigMapKeyRef = &helpers.ConfigMapKeySelector{
+		ConfigMapReference: helpers.ConfigMapReference{Name: "values", Namespace: "default"},
+		Key:                "values.json",
+	}
+
+	return cr
+}
+
+// newExternal returns the external client for the local repositories.
+func newExternal(t *testing.T, objs ...client.Object) *external {
+	t.Helper()
+
+	srv := newDeploymentService(t)
+
+	objs = append(objs, newValues(`{"name": "demo"}`))
+
+	return &external{
+		kube: fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(objs...).Build(),
+		log:  logging.NewNopLogger(),
+		cfg: &clients.Config{
+			DeploymentServiceUrl: srv.URL,
+			HTTPClient:           srv.Client(),
+		},
+		rec: record.NewFakeRecorder(100),
+	}
+}
+
+// newTemplate returns the template repository.
+func newTemplate(t *testing.T) string {
+	t.Helper()
+
+	return newRemote(t, "main", map[string]string{
+		"skel/README.md": "# {{name}}",
+	})
+}
+
+func TestCreateTargetBranch(t *testing.T) {
+	ctx := context.Background()
+
+	table := []struct {
+		name   string
+		branch string
+		want   string
+		// a file already on the branch the scaffold is committed on
+		kept string
+	}{
+		{"remote HEAD branch", "", "trunk", "LICENSE"},
+		{"existing branch", "develop", "develop", "CHANGELOG.md"},
+		// forked from the remote HEAD branch
+		{"new branch", "feature", "feature", "LICENSE"},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			from := newTemplate(t)
+			to := newRemote(t, "trunk", map[string]string{"LICENSE": "MIT"})
+			commitFiles(t, to, "develop", map[string]string{"CHANGELOG.md": "none"})
+
+			cr := newTestRepo(from, to)
+			if len(tc.branch) > 0 {
+				cr.Spec.ForProvider.ToRepo.Branch = helpers.StringPtr(tc.branch)
+			}
+
+			e := newExternal(t)
+
+			obs, err := e.Observe(ctx, cr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if obs.ResourceExists {
+				t.Fatalf("expected the scaffold not to exist yet")
+			}
+
+			if _, err := e.Create(ctx, cr); err != nil {
+				t.Fatal(err)
+			}
+
+			if got, ok := remoteFile(t, to, tc.want, "README.md"); !ok || got != "# demo" {
+				t.Fatalf("branch %s: unexpected README.md %q", tc.want, got)
+			}
+			if _, ok := remoteFile(t, to, tc.want, "deployment.yaml"); !ok {
+				t.Fatalf("branch %s: missing deployment.yaml", tc.want)
+			}
+			if _, ok := remoteFile(t, to, tc.want, tc.kept); !ok {
+				t.Fatalf("branch %s: missing %s", tc.want, tc.kept)
+			}
+			if _, ok := remoteFile(t, to, "trunk", "deployment.yaml"); ok && tc.want != "trunk" {
+				t.Fatalf("branch trunk: unexpected deployment.yaml")
+			}
+
+			if got := helpers.StringValue(cr.Status.AtProvider.Branch); got != tc.want {
+				t.Fatalf("got branch %q, want %q", got, tc.want)
+			}
+
+			obs, err = e.Observe(ctx, cr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !obs.ResourceExists {
+				t.Fatalf("expected the scaffold to exist")
+			}
+		})
+	}
+}
+
+// fakeManager provides the reconcilers with the client and the scheme.
+type fakeManager struct {
+	manager.Manager
+
+	client client.Client
+	scheme *runtime.Scheme
+}
+
+func (m *fakeManager) GetClient() client.Client {
+	return m.client
+}
+
+func (m *fakeManager) GetScheme() *runtime.Scheme {
+	return m.scheme
+}
+
+// newReconciler returns the managed reconciler of the Repo objects, with
+// the recorder of its events.
+func newReconciler(t *testing.T, objs ...client.Object) (*managed.Reconciler, client.Client, *record.FakeRecorder) {
+	t.Helper()
+
+	srv := newDeploymentService(t)
+
+	pc := &v1alpha1.ProviderConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec:       v1alpha1.ProviderConfigSpec{DeploymentServiceUrl: srv.URL},
+	}
+	objs = append(objs, pc, newValues(`{"name": "demo"}`))
+
+	s := newScheme(t)
+	kube := fake.NewClientBuilder().WithScheme(s).WithObjects(objs...).Build()
+
+	rec := record.NewFakeRecorder(100)
+	failures := newFailureTracker()
+
+	mgr := &reasonManager{Manager: &fakeManager{client: kube, scheme: s}, failures: failures}
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(repov1alpha1.RepoGroupVersionKind),
+		managed.WithExternalConnecter(&connector{
+			kube:     kube,
+			log:      logging.NewNopLogger(),
+			recorder: rec,
+			failures: failures,
+		}),
+		managed.WithLogger(logging.NewNopLogger()),
+		managed.WithRecorder(&reasonRecorder{
+			Recorder: event.NewAPIRecorder(rec),
+			failures: failures,
+		}))
+
+	return r, kube, rec
+}
+
+func TestReconcileFromRepoCommitId(t *testing.T) {
+	ctx := context.Background()
+
+	from := newTemplate(t)
+	to := newRemote(t, "main", map[string]string{"LICENSE": "MIT"})
+
+	cr := newTestRepo(from, to)
+	cr.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+	// names the ProviderConfigUsage, the fake client does not set it
+	cr.SetUID("2f9c4d1e")
+
+	r, kube, _ := newReconciler(t, cr)
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: cr.Name}}
+	// the first reconcile creates the scaffold, the second observes it
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, ok := remoteFile(t, to, "main", "deployment.yaml"); !ok {
+		t.Fatalf("missing deployment.yaml")
+	}
+
+	got := &repov1alpha1.Repo{}
+	if err := kube.Get(ctx, req.NamespacedName, got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := remoteCommit(t, from, "main").Hash.String()
+	if id := helpers.StringValue(got.Status.AtProvider.FromRepoCommitId); id != want {
+		t.Fatalf("got fromRepoCommitId %q, want %q", id, want)
+	}
+	if id := got.GetAnnotations()[annFromRepoCommitId]; id != want {
+		t.Fatalf("got annotation %q, want %q", id, want)
+	}
+	if c := got.GetCondition(xpv1.TypeReady); c.Reason != xpv1.ReasonAvailable {
+		t.Fatalf("got ready reason %q, want %q", c.Reason, xpv1.ReasonAvailable)
+	}
+}
+
+func TestObserveBranchTip(t *testing.T) {
+	ctx := context.Background()
+
+	to := newRemote(t, "main", map[string]string{"LICENSE": "MIT"})
+	tip := remoteCommit(t, to, "main").Hash.String()
+
+	cr := newTestRepo(newTemplate(t), to)
+
+	e := newExternal(t)
+
+	// a tip other than the pushed one is looked for the claim
+	cr.Status.AtProvider.CommitId = helpers.StringPtr("0123456789abcdef0123456789abcdef01234567")
+
+	obs, err := e.Observe(ctx, cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obs.ResourceExists {
+		t.Fatalf("expected the scaffold not to exist without deployment.yaml")
+	}
+
+	// the pushed tip is trusted without reading its tree
+	cr.Status.AtProvider.CommitId = helpers.StringPtr(tip)
+
+	obs, err = e.Observe(ctx, cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !obs.ResourceExists {
+		t.Fatalf("expected the scaffold to exist at the pushed tip")
+	}
+
+	// the tip moved: the claim is found again and the new tip recorded
+	commitFiles(t, to, "main", map[string]string{"deployment.yaml": "kind: Deployment"})
+	tip = commitFiles(t, to, "main", map[string]string{"CHANGELOG.md": "none"})
+
+	obs, err = e.Observe(ctx, cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !obs.ResourceExists {
+		t.Fatalf("expected the scaffold to exist after the tip moved")
+	}
+	if got := helpers.StringValue(cr.Status.AtProvider.CommitId); got != tip {
+		t.Fatalf("got commitId %q, want %q", got, tip)
+	}
+}
+
+func TestReconcileGitFailure(t *testing.T) {
+	ctx := context.Background()
+
+	missing := filepath.Join(t.TempDir(), "missing.git")
+
+	table := []struct {
+		name string
+		from string
+		to   string
+	}{
+		// reported by Observe
+		{"missing target", newTemplate(t), missing},
+		// reported by Create, across the critical annotations update
+		{"missing template", missing, newRemote(t, "main", map[string]string{"LICENSE": "MIT"})},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			cr := newTestRepo(tc.from, tc.to)
+			cr.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+			cr.SetUID("2f9c4d1e")
+
+			r, kube, rec := newReconciler(t, cr)
+
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: cr.Name}}
+			if _, err := r.Reconcile(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+
+			got := &repov1alpha1.Repo{}
+			if err := kube.Get(ctx, req.NamespacedName, got); err != nil {
+				t.Fatal(err)
+			}
+
+			c := got.GetCondition(xpv1.TypeSynced)
+			if c.Status != corev1.ConditionFalse || c.Reason != ReasonRepositoryNotFound {
+				t.Fatalf("got Synced %s (%s), want %s (%s)", c.Status, c.Reason, corev1.ConditionFalse, ReasonRepositoryNotFound)
+			}
+
+			if !hasEvent(rec, corev1.EventTypeWarning, ReasonRepositoryNotFound) {
+				t.Fatalf("missing %s warning event", ReasonRepositoryNotFound)
+			}
+		})
+	}
+}
+
+func TestReconcileGitFailureResolved(t *testing.T) {
+	ctx := context.Background()
+
+	to := newRemote(t, "main", map[string]string{"LICENSE": "MIT"})
+
+	cr := newTestRepo(filepath.Join(t.TempDir(), "missing.git"), to)
+	cr.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+	cr.SetUID("2f9c4d1e")
+
+	r, kube, _ := newReconciler(t, cr)
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: cr.Name}}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	got := &repov1alpha1.Repo{}
+	if err := kube.Get(ctx, req.NamespacedName, got); err != nil {
+		t.Fatal(err)
+	}
+	got.Spec.ForProvider.FromRepo.Url = newTemplate(t)
+	if err := kube.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := kube.Get(ctx, req.NamespacedName, got); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := remoteFile(t, to, "main", "deployment.yaml"); !ok {
+		t.Fatalf("missing deployment.yaml")
+	}
+
+	c := got.GetCondition(xpv1.TypeSynced)
+	if c.Status != corev1.ConditionTrue || c.Reason != xpv1.ReasonReconcileSuccess {
+		t.Fatalf("got Synced %s (%s), want %s (%s)", c.Status, c.Reason, corev1.ConditionTrue, xpv1.ReasonReconcileSuccess)
+	}
+}
+
+// hasEvent tells if an event of the type and reason was recorded.
+func hasEvent(rec *record.FakeRecorder, typ string, reason xpv1.ConditionReason) bool {
+	for {
+		select {
+		case el := <-rec.Events:
+			if strings.HasPrefix(el, typ+" "+string(reason)+" ") {
+				return true
+			}
+		default:
+			return false
+		}
+	}
+}
+
+func TestCreateEmptyRepo(t *testing.T) {
+	ctx := context.Background()
+
+	table := []struct {
+		branch string
+		want   string
+	}{
+		{"", defaultBranch},
+		{"develop", "develop"},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.want, func(t *testing.T) {
+			// no commits yet
+			to := newRemote(t, "trunk", nil)
+
+			cr := newTestRepo(newTemplate(t), to)
+			if len(tc.branch) > 0 {
+				cr.Spec.ForProvider.ToRepo.Branch = helpers.StringPtr(tc.branch)
+			}
+
+			e := newExternal(t)
+
+			obs, err := e.Observe(ctx, cr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if obs.ResourceExists {
+				t.Fatalf("expected the scaffold not to exist in the empty repo")
+			}
+
+			if _, err := e.Create(ctx, cr); err != nil {
+				t.Fatal(err)
+			}
+
+			commit := remoteCommit(t, to, tc.want)
+			if commit == nil {
+				t.Fatalf("branch %s not pushed", tc.want)
+			}
+			if commit.NumParents() != 0 {
+				t.Fatalf("expected the scaffold to be the first commit")
+			}
+			if got, ok := remoteFile(t, to, tc.want, "README.md"); !ok || got != "# demo" {
+				t.Fatalf("branch %s: unexpected README.md %q", tc.want, got)
+			}
+
+			if got := helpers.StringValue(cr.Status.AtProvider.Branch); got != tc.want {
+				t.Fatalf("got branch %q, want %q", got, tc.want)
+			}
+			if got := helpers.StringValue(cr.Status.AtProvider.CommitId); got != commit.Hash.String() {
+				t.Fatalf("got commitId %q, want %q", got, commit.Hash)
+			}
+		})
+	}
+}
+
+// fakeHosting keeps the last pull request opened on the hosting service;
+// when blocked, the calls wait for the context to expire.
+type fakeHosting struct {
+	pr     *hosting.PullRequest
+	opened int
+	block  bool
+}
+
+func (h *fakeHosting) wait(ctx context.Context) error {
+	if !h.block {
+		return nil
+	}
+
+	<-ctx.Done()
+	return &url.Error{Op: "Get", URL: "https://example.com/api", Err: ctx.Err()}
+}
+
+func (h *fakeHosting) CreateRepo(ctx context.Context, opts *hosting.CreateRepoOpts) error {
+	return h.wait(ctx)
+}
+
+func (h *fakeHosting) OpenPullRequest(ctx context.Context, opts *hosting.PullRequestOpts) (*hosting.PullRequest, error) {
+	if err := h.wait(ctx); err != nil {
+		return nil, err
+	}
+	if h.pr == nil || h.pr.State != hosting.PullRequestOpen {
+		h.opened++
+		h.pr = &hosting.PullRequest{
+			Url:   fmt.Sprintf("https://example.com/demo/pulls/%d", h.opened),
+			State: hosting.PullRequestOpen,
+		}
+	}
+
+	return h.pr, nil
+}
+
+func (h *fakeHosting) FindPullRequest(ctx context.Context, opts *hosting.PullRequestOpts) (*hosting.PullRequest, error) {
+	if err := h.wait(ctx); err != nil {
+		return nil, err
+	}
+	return h.pr, nil
+}
+
+// remoteTag returns the commit tagged by the remote annotated tag, nil if missing.
+func remoteTag(t *testing.T, dir, name string) *object.Commit {
+	t.Helper()
+
+	r, err := gogit.PlainOpen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref, err := r.Tag(name)
+	if err != nil {
+		return nil
+	}
+
+	tag, err := r.TagObject(ref.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commit, err := tag.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return commit
+}
+
+// observe observes the Repo, failing the test on error.
+func observe(t *testing.T, e *external, cr *repov1alpha1.Repo) managed.ExternalObservation {
+	t.Helper()
+
+	obs, err := e.Observe(context.Background(), cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return obs
+}
+
+func TestTagMissing(t *testing.T) {
+	ctx := context.Background()
+
+	to := newRemote(t, "main", map[string]string{"LICENSE": "MIT"})
+
+	cr := newTestRepo(newTemplate(t), to)
+	cr.Spec.ForProvider.Tag = &repov1alpha1.TagOpts{Name: "v0.0.1"}
+
+	e := newExternal(t)
+
+	if _, err := e.Create(ctx, cr); err != nil {
+		t.Fatal(err)
+	}
+
+	scaffold := remoteCommit(t, to, "main")
+	if tagged := remoteTag(t, to, "v0.0.1"); tagged == nil || tagged.Hash != scaffold.Hash {
+		t.Fatalf("expected the tag on the scaffold commit %s", scaffold.Hash)
+	}
+
+	if obs := observe(t, e, cr); !obs.ResourceExists || !obs.ResourceUpToDate {
+		t.Fatalf("expected the tagged scaffold to be up to date")
+	}
+
+	// as if the tag push failed
+	r, err := gogit.PlainOpen(to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeleteTag("v0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if obs := observe(t, e, cr); !obs.ResourceExists || obs.ResourceUpToDate {
+		t.Fatalf("expected the scaffold without the tag not to be up to date")
+	}
+
+	if _, err := e.Update(ctx, cr); err != nil {
+		t.Fatal(err)
+	}
+
+	if tagged := remoteTag(t, to, "v0.0.1"); tagged == nil || tagged.Hash != scaffold.Hash {
+		t.Fatalf("expected the tag on the branch tip %s", scaffold.Hash)
+	}
+	if obs := observe(t, e, cr); !obs.ResourceUpToDate {
+		t.Fatalf("expected the tagged scaffold to be up to date")
+	}
+}
+
+func TestTagDeferredUntilMerged(t *testing.T) {
+	ctx := context.Background()
+
+	to := newRemote(t, "main", map[string]string{"LICENSE": "MIT"})
+
+	cr := newTestRepo(newTemplate(t), to)
+	cr.Spec.ForProvider.ToRepo.Delivery = &repov1alpha1.DeliveryOpts{Mode: helpers.StringPtr(deliveryPullRequest)}
+	cr.Spec.ForProvider.Tag = &repov1alpha1.TagOpts{Name: "v0.0.1"}
+
+	h := &fakeHosting{}
+
+	e := newExternal(t)
+	e.cfg.Hosting = h
+
+	if _, err := e.Create(ctx, cr); err != nil {
+		t.Fatal(err)
+	}
+
+	if h.pr == nil {
+		t.Fatalf("expected a pull request")
+	}
+	if _, ok := remoteFile(t, to, featureBranchPrefix+testDeploymentId, "deployment.yaml"); !ok {
+		t.Fatalf("missing deployment.yaml on the feature branch")
+	}
+	if remoteTag(t, to, "v0.0.1") != nil {
+		t.Fatalf("expected the tag to wait for the merge")
+	}
+
+	if obs := observe(t, e, cr); !obs.ResourceExists || !obs.ResourceUpToDate {
+		t.Fatalf("expected the open pull request to be up to date")
+	}
+
+	// merged by the hosting service
+	merged := commitFiles(t, to, "main", map[string]string{"deployment.yaml": "kind: Deployment"})
+	h.pr.State = hosting.PullRequestMerged
+
+	if obs := observe(t, e, cr); !obs.ResourceExists || obs.ResourceUpToDate {
+		t.Fatalf("expected the merged pull request without the tag not to be up to date")
+	}
+
+	if _, err := e.Update(ctx, cr); err != nil {
+		t.Fatal(err)
+	}
+
+	if tagged := remoteTag(t, to, "v0.0.1"); tagged == nil || tagged.Hash.String() != merged {
+		t.Fatalf("expected the tag on the base branch tip %s", merged)
+	}
+	if obs := observe(t, e, cr); !obs.ResourceUpToDate {
+		t.Fatalf("expected the tagged scaffold to be up to date")
+	}
+}
+
+func TestPullRequestClosed(t *testing.T) {
+	ctx := context.Background()
+
+	to := newRemote(t, "main", map[string]string{"LICENSE": "MIT"})
+
+	cr := newTestRepo(newTemplate(t), to)
+	cr.Spec.ForProvider.ToRepo.Delivery = &repov1alpha1.DeliveryOpts{Mode: helpers.StringPtr(deliveryPullRequest)}
+
+	h := &fakeHosting{}
+
+	e := newExternal(t)
+	e.cfg.Hosting = h
+
+	if _, err := e.Create(ctx, cr); err != nil {
+		t.Fatal(err)
+	}
+
+	// closed without merging by the hosting service
+	h.pr.State = hosting.PullRequestClosed
+
+	if obs := observe(t, e, cr); obs.ResourceExists {
+		t.Fatalf("expected the closed pull request to be opened again")
+	}
+	if got := helpers.StringValue(cr.Status.AtProvider.PullRequestState); got != hosting.PullRequestClosed {
+		t.Fatalf("got pull request state %q, want %q", got, hosting.PullRequestClosed)
+	}
+	if !hasEvent(e.rec.(*record.FakeRecorder), corev1.EventTypeWarning, "PullRequestClosed") {
+		t.Fatalf("expected a PullRequestClosed warning event")
+	}
+
+	if _, err := e.Create(ctx, cr); err != nil {
+		t.Fatal(err)
+	}
+
+	if h.opened != 2 {
+		t.Fatalf("got %d pull requests opened, want 2", h.opened)
+	}
+	if got := helpers.StringValue(cr.Status.AtProvider.PullRequestUrl); got != h.pr.Url {
+		t.Fatalf("got pull request %q, want %q", got, h.pr.Url)
+	}
+	if obs := observe(t, e, cr); !obs.ResourceExists || !obs.ResourceUpToDate {
+		t.Fatalf("expected the new pull request to be up to date")
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	ctx := context.Background()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	table := []struct {
+		name  string
+		setup func(e *external, cr *repov1alpha1.Repo)
+	}{
+		{"deployment service", func(e *external, cr *repov1alpha1.Repo) {
+			e.cfg.DeploymentServiceUrl = slow.URL
+			e.cfg.Timeouts.Deployment = 50 * time.Millisecond
+		}},
+		{"pull request opening", func(e *external, cr *repov1alpha1.Repo) {
+			cr.Spec.ForProvider.ToRepo.Delivery = &repov1alpha1.DeliveryOpts{Mode: helpers.StringPtr(deliveryPullRequest)}
+			e.cfg.Hosting = &fakeHosting{block: true}
+			e.cfg.Timeouts.Hosting = 50 * time.Millisecond
+		}},
+		{"repository creation", func(e *external, cr *repov1alpha1.Repo) {
+			cr.Spec.ForProvider.ToRepo.Url = filepath.Join(t.TempDir(), "missing.git")
+			cr.Spec.ForProvider.ToRepo.CreateIfMissing = &repov1alpha1.CreateRepoOpts{}
+			e.cfg.Hosting = &fakeHosting{block: true}
+			e.cfg.Timeouts.Hosting = 50 * time.Millisecond
+		}},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			cr := newTestRepo(newTemplate(t), newRemote(t, "main", map[string]string{"LICENSE": "MIT"}))
+
+			e := newExternal(t)
+			tc.setup(e, cr)
+
+			_, err := e.Create(ctx, cr)
+			if reason, _ := failureReason(err); reason != ReasonNetworkTimeout {
+				t.Fatalf("got reason %q (%v), want %q", reason, err, ReasonNetworkTimeout)
+			}
+		})
+	}
+}
+
+func TestCreateRenderFunc(t *testing.T) {
+	co := &repo.CopyOpts{}
+	createRenderFunc(co, map[string]interface{}{"name": "R&D's app"})
+
+	// the names are plain text, unlike the file contents
+	got, err := co.RenderNameFunc("{{name}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "R&D's app"; got != want {
+		t.Fatalf("got name %q, want %q", got, want)
+	}
+}
+
+func TestLoadControlFiles(t *testing.T) {
+	fromRepo, err := git.Init(&git.CloneOpts{URL: "file:///template.git"}, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	co := &repo.CopyOpts{FromRepo: fromRepo}
+
+	// the missing control files are no errors
+	if err := loadIgnoreFileEventually(co); err != nil {
+		t.Fatalf("%s: %v", repo.IgnoreFile, err)
+	}
+	if err := loadExcludeFileEventually(co); err != nil {
+		t.Fatalf("%s: %v", repo.ExcludeFile, err)
+	}
+	if err := loadRulesFileEventually(co, nil); err != nil {
+		t.Fatalf("%s: %v", repo.RulesFile, err)
+	}
+	if co.Ignore != nil || co.Exclude != nil || co.Rules != nil {
+		t.Fatalf("unexpected control files loaded")
+	}
+
+	files := map[string]string{
+		repo.IgnoreFile:  "*.png",
+		repo.ExcludeFile: "docs/",
+		repo.RulesFile:   "Dockerfile  containerized",
+	}
+	for name, content := range files {
+		f, err := fromRepo.FS().Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+		f.Close()
+	}
+
+	if err := loadIgnoreFileEventually(co); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadExcludeFileEventually(co); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadRulesFileEventually(co, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if !co.Ignore.MatchesPath("logo.png") || !co.Exclude.MatchesPath("docs/") || co.Rules.Excluding("Dockerfile") == nil {
+		t.Fatalf("control files not loaded")
+	}
+}
@@ -0,0 +1,89 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/krateoplatformops/provider-git/pkg/clients/deployment"
+	"github.com/krateoplatformops/provider-git/pkg/clients/git"
+	"github.com/krateoplatformops/provider-git/pkg/clients/hosting"
+)
+
+// withTimeout bounds the context by the timeout, if any.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+func (e *external) getDeployment(ctx context.Context, deploymentId string) ([]byte, error) {
+	ctx, cancel := withTimeout(ctx, e.cfg.Timeouts.Deployment)
+	defer cancel()
+
+	return deployment.Get(ctx, e.cfg.HTTPClient, e.cfg.DeploymentServiceUrl, deploymentId)
+}
+
+func (e *external) clone(ctx context.Context, opts *git.CloneOpts) (*git.Repo, error) {
+	ctx, cancel := withTimeout(ctx, e.cfg.Timeouts.Clone)
+	defer cancel()
+
+	return git.Clone(ctx, opts)
+}
+
+func (e *external) branchTip(ctx context.Context, opts *git.CloneOpts, name string) (string, string, error) {
+	ctx, cancel := withTimeout(ctx, e.cfg.Timeouts.Fetch)
+	defer cancel()
+
+	return git.BranchTip(ctx, opts, name)
+}
+
+func (e *external) hasFile(ctx context.Context, opts *git.CloneOpts, branch, path string) (bool, error) {
+	ctx, cancel := withTimeout(ctx, e.cfg.Timeouts.Fetch)
+	defer cancel()
+
+	return git.HasFile(ctx, opts, branch, path)
+}
+
+func (e *external) tags(ctx context.Context, opts *git.CloneOpts) ([]string, error) {
+	ctx, cancel := withTimeout(ctx, e.cfg.Timeouts.Fetch)
+	defer cancel()
+
+	return git.Tags(ctx, opts)
+}
+
+func (e *external) push(ctx context.Context, repo *git.Repo, opts *git.PushOpts) error {
+	ctx, cancel := withTimeout(ctx, e.cfg.Timeouts.Push)
+	defer cancel()
+
+	return repo.Push(ctx, opts)
+}
+
+func (e *external) pushTags(ctx context.Context, repo *git.Repo) error {
+	ctx, cancel := withTimeout(ctx, e.cfg.Timeouts.Push)
+	defer cancel()
+
+	return repo.PushTags(ctx, e.cfg.Insecure)
+}
+
+func (e *external) createRepo(ctx context.Context, opts *hosting.CreateRepoOpts) error {
+	ctx, cancel := withTimeout(ctx, e.cfg.Timeouts.Hosting)
+	defer cancel()
+
+	return e.cfg.Hosting.CreateRepo(ctx, opts)
+}
+
+func (e *external) openPullRequest(ctx context.Context, opts *hosting.PullRequestOpts) (*hosting.PullRequest, error) {
+	ctx, cancel := withTimeout(ctx, e.cfg.Timeouts.Hosting)
+	defer cancel()
+
+	return e.cfg.Hosting.OpenPullRequest(ctx, opts)
+}
+
+func (e *external) queryPullRequest(ctx context.Context, opts *hosting.PullRequestOpts) (*hosting.PullRequest, error) {
+	ctx, cancel := withTimeout(ctx, e.cfg.Timeouts.Hosting)
+	defer cancel()
+
+	return e.cfg.Hosting.FindPullRequest(ctx, opts)
+}